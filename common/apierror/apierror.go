@@ -0,0 +1,160 @@
+// Package apierror is the error model of the Polymove HTTP services: handler errors are classified by kind,
+// which picks the HTTP status, and carry a stable machine-readable code for clients.
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error kinds; match them with errors.Is.
+var (
+	ErrValidation          = errors.New("validation failed")
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrForbidden           = errors.New("forbidden")
+	ErrNotFound            = errors.New("not found")
+	ErrConflict            = errors.New("conflict")
+	ErrUnprocessable       = errors.New("unprocessable")
+	ErrTooManyRequests     = errors.New("too many requests")
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+)
+
+// Generic codes, used when an error of a kind carries no code of its own.
+const (
+	CodeValidation          = "validation_failed"
+	CodeUnauthorized        = "unauthorized"
+	CodeForbidden           = "forbidden"
+	CodeNotFound            = "not_found"
+	CodeConflict            = "conflict"
+	CodeUnprocessable       = "unprocessable"
+	CodeTooManyRequests     = "too_many_requests"
+	CodeUpstreamUnavailable = "upstream_unavailable"
+	CodeInternal            = "internal_error"
+)
+
+// Coder is implemented by errors that carry their own stable code.
+type Coder interface {
+	ErrorCode() string
+}
+
+// Error is a handler error of one kind with a stable code; Err is the optional cause.
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Is makes errors.Is(err, ErrNotFound) and the other kinds match.
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) ErrorCode() string {
+	return e.Code
+}
+
+// Validation returns a 400 error for a malformed or invalid request.
+func Validation(code, format string, args ...interface{}) error {
+	return &Error{Kind: ErrValidation, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Unauthorized returns a 401 error for a request without valid credentials.
+func Unauthorized(code, format string, args ...interface{}) error {
+	return &Error{Kind: ErrUnauthorized, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Forbidden returns a 403 error for an authenticated caller lacking the rights for a request.
+func Forbidden(code, format string, args ...interface{}) error {
+	return &Error{Kind: ErrForbidden, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// NotFound returns a 404 error for a missing resource.
+func NotFound(code, format string, args ...interface{}) error {
+	return &Error{Kind: ErrNotFound, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Conflict returns a 409 error for a request clashing with the current state.
+func Conflict(code, format string, args ...interface{}) error {
+	return &Error{Kind: ErrConflict, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Unprocessable returns a 422 error for a valid request that cannot be applied to the current state.
+func Unprocessable(code, format string, args ...interface{}) error {
+	return &Error{Kind: ErrUnprocessable, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// TooManyRequests returns a 429 error for a caller that must slow down.
+func TooManyRequests(code, format string, args ...interface{}) error {
+	return &Error{Kind: ErrTooManyRequests, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// UpstreamUnavailable returns a 502 error for a dependency that could not answer; err is the cause.
+func UpstreamUnavailable(code, message string, err error) error {
+	return &Error{Kind: ErrUpstreamUnavailable, Code: code, Message: message, Err: err}
+}
+
+// Status picks the HTTP status matching err; errors of no kind are 500.
+func Status(err error) int {
+	switch {
+	case errors.Is(err, ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrUnprocessable):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrTooManyRequests):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrUpstreamUnavailable):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Code returns the stable code of err: its own when it has one, else the generic code of its kind.
+func Code(err error) string {
+	var coder Coder
+	if errors.As(err, &coder) && coder.ErrorCode() != "" {
+		return coder.ErrorCode()
+	}
+
+	switch Status(err) {
+	case http.StatusBadRequest:
+		return CodeValidation
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusUnprocessableEntity:
+		return CodeUnprocessable
+	case http.StatusTooManyRequests:
+		return CodeTooManyRequests
+	case http.StatusBadGateway:
+		return CodeUpstreamUnavailable
+	default:
+		return CodeInternal
+	}
+}
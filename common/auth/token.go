@@ -0,0 +1,149 @@
+// Package auth issues and verifies the JWTs shared by the Polymove HTTP services and hashes user passwords.
+// Tokens are HS256-signed with the JWT_SECRET every service is configured with.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// User roles.
+const (
+	RoleStudent  = "student"
+	RoleAdmin    = "admin"
+	RoleEmployer = "employer"
+	// RoleService is held by services calling each other on no user's behalf; no account has it.
+	RoleService = "service"
+)
+
+const (
+	// SecretEnv names the environment variable holding the token signing secret.
+	SecretEnv = "JWT_SECRET"
+	// MinSecretLength is the shortest accepted signing secret, in bytes.
+	MinSecretLength = 32
+)
+
+var (
+	ErrMissingToken = errors.New("missing bearer token")
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token expired")
+)
+
+// Claims identify the user a token was issued to. StudentID is set for students, EmployerID for employers and
+// Service for service tokens.
+type Claims struct {
+	UserID     int    `json:"user_id,omitempty"`
+	Email      string `json:"email,omitempty"`
+	Role       string `json:"role"`
+	StudentID  int    `json:"student_id,omitempty"`
+	EmployerID int    `json:"employer_id,omitempty"`
+	Service    string `json:"service,omitempty"`
+
+	jwt.RegisteredClaims
+}
+
+// HasRole reports whether the claims carry one of roles.
+func (c Claims) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if c.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Subject names the caller for logs and audit trails: the user's email, or the service name.
+func (c Claims) Subject() string {
+	if c.Role == RoleService {
+		return "service:" + c.Service
+	}
+	return c.Email
+}
+
+// ValidRole reports whether role is one of the user roles.
+func ValidRole(role string) bool {
+	return role == RoleStudent || role == RoleAdmin || role == RoleEmployer
+}
+
+// Issue signs claims into a token valid for ttl from now; it returns the token and its expiry.
+func Issue(secret []byte, claims Claims, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+	expiresAt := now.Add(ttl)
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(expiresAt)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign token: %w", err)
+	}
+	return token, expiresAt, nil
+}
+
+// IssueService signs a token identifying service, valid for ttl from now.
+func IssueService(secret []byte, service string, ttl time.Duration) (string, time.Time, error) {
+	return Issue(secret, Claims{Role: RoleService, Service: service}, ttl)
+}
+
+// Verify checks the signature and expiry of token and returns its claims. Only HS256 tokens are accepted.
+func Verify(secret []byte, token string, now time.Time) (Claims, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(func() time.Time { return now }),
+	)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return Claims{}, ErrExpiredToken
+	}
+	if err != nil || !(ValidRole(claims.Role) || claims.Role == RoleService) {
+		return Claims{}, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// BearerToken returns the token of the request's "Authorization: Bearer" header.
+func BearerToken(r *http.Request) (string, error) {
+	header := strings.TrimSpace(r.Header.Get("Authorization"))
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", ErrMissingToken
+	}
+	return strings.TrimSpace(token), nil
+}
+
+// publishedSecret is the development secret the compose file once shipped with; anyone can sign tokens with it.
+const publishedSecret = "polymove-dev-secret-change-me-in-production"
+
+// SecretFromEnv reads the signing secret from JWT_SECRET, refusing short and published secrets.
+func SecretFromEnv() ([]byte, error) {
+	secret := os.Getenv(SecretEnv)
+	if len(secret) < MinSecretLength {
+		return nil, fmt.Errorf("%s must be set to at least %d characters", SecretEnv, MinSecretLength)
+	}
+	if secret == publishedSecret {
+		return nil, fmt.Errorf("%s is the published development secret, generate your own", SecretEnv)
+	}
+	return []byte(secret), nil
+}
+
+type claimsKey struct{}
+
+// WithClaims returns a copy of ctx carrying the claims of the authenticated caller.
+func WithClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// FromContext returns the claims stored by WithClaims; ok is false for unauthenticated requests.
+func FromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(Claims)
+	return claims, ok
+}
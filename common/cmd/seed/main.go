@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -37,6 +38,7 @@ var seedOffers = []common.Offer{
 		StartDate: "2026-06-01",
 		EndDate:   "2026-08-31",
 		Available: true,
+		Seats:     2,
 	},
 	{
 		Title:     "Cybersecurity Operations Intern",
@@ -47,6 +49,7 @@ var seedOffers = []common.Offer{
 		StartDate: "2026-06-15",
 		EndDate:   "2026-09-15",
 		Available: true,
+		Seats:     1,
 	},
 	{
 		Title:     "Data Analyst Intern",
@@ -57,6 +60,7 @@ var seedOffers = []common.Offer{
 		StartDate: "2026-05-15",
 		EndDate:   "2026-08-15",
 		Available: true,
+		Seats:     1,
 	},
 	{
 		Title:     "Cloud Network Intern",
@@ -67,6 +71,7 @@ var seedOffers = []common.Offer{
 		StartDate: "2026-06-01",
 		EndDate:   "2026-09-01",
 		Available: true,
+		Seats:     1,
 	},
 	{
 		Title:     "Full Stack Developer Intern",
@@ -77,6 +82,7 @@ var seedOffers = []common.Offer{
 		StartDate: "2026-07-01",
 		EndDate:   "2026-10-01",
 		Available: true,
+		Seats:     3,
 	},
 }
 
@@ -86,6 +92,16 @@ func main() {
 
 	client := &http.Client{Timeout: 10 * time.Second}
 
+	adminPassword := os.Getenv("ADMIN_PASSWORD")
+	if adminPassword == "" {
+		exitWithError(fmt.Errorf("ADMIN_PASSWORD must be set to the password of the compose stack's admin, see .env"))
+	}
+	token, err := login(client, polytechURL, envOrDefault("ADMIN_EMAIL", "admin@polymove.local"), adminPassword)
+	if err != nil {
+		exitWithError(fmt.Errorf("log in as admin: %w", err))
+	}
+	bearerToken = token
+
 	if err := seedPolytechStudents(client, polytechURL); err != nil {
 		exitWithError(err)
 	}
@@ -99,6 +115,30 @@ func main() {
 	fmt.Printf("Erasmumu: %s\n", erasMumuURL)
 	fmt.Printf("Students seeded: %d\n", len(seedStudents))
 	fmt.Printf("Offers seeded: %d\n", len(seedOffers))
+	fmt.Printf("Student accounts: <first>.<last>@polymove.local / %s\n", studentPassword())
+}
+
+// bearerToken authenticates the seeding requests as the admin, see login.
+var bearerToken string
+
+func login(client *http.Client, baseURL, email, password string) (string, error) {
+	var response struct {
+		Token string `json:"token"`
+	}
+	err := postJSON(client, baseURL+"/auth/login", map[string]string{"email": email, "password": password}, http.StatusOK, &response)
+	if err != nil {
+		return "", err
+	}
+	return response.Token, nil
+}
+
+func studentPassword() string {
+	return envOrDefault("SEED_STUDENT_PASSWORD", "polymove-student")
+}
+
+// studentEmail derives a seeded student's login, as in "alice.martin@polymove.local".
+func studentEmail(student Student) string {
+	return strings.Join(strings.Fields(strings.ToLower(student.Name)), ".") + "@polymove.local"
 }
 
 func seedPolytechStudents(client *http.Client, baseURL string) error {
@@ -108,18 +148,41 @@ func seedPolytechStudents(client *http.Client, baseURL string) error {
 	}
 
 	for _, student := range seedStudents {
-		if hasStudent(existing, student) {
+		if found, ok := findStudent(existing, student); ok {
 			fmt.Printf("Skipping existing student: %s (%s)\n", student.Name, student.Domain)
-			continue
+			student = found
+		} else {
+			if err := postJSON(client, baseURL+"/student", student, http.StatusCreated, &student); err != nil {
+				return fmt.Errorf("create student %q: %w", student.Name, err)
+			}
+			fmt.Printf("Created student: %s (%s)\n", student.Name, student.Domain)
 		}
 
-		if err := postJSON(client, baseURL+"/student", student); err != nil {
-			return fmt.Errorf("create student %q: %w", student.Name, err)
+		if err := seedStudentAccount(client, baseURL, student); err != nil {
+			return fmt.Errorf("create account of student %q: %w", student.Name, err)
 		}
+	}
 
-		fmt.Printf("Created student: %s (%s)\n", student.Name, student.Domain)
+	return nil
+}
+
+func seedStudentAccount(client *http.Client, baseURL string, student Student) error {
+	account := map[string]interface{}{
+		"email":      studentEmail(student),
+		"password":   studentPassword(),
+		"role":       "student",
+		"student_id": student.ID,
+	}
+	err := postJSON(client, baseURL+"/users", account, http.StatusCreated, nil)
+	if errors.Is(err, errAlreadyExists) {
+		fmt.Printf("Skipping existing account: %s\n", studentEmail(student))
+		return nil
+	}
+	if err != nil {
+		return err
 	}
 
+	fmt.Printf("Created account: %s\n", studentEmail(student))
 	return nil
 }
 
@@ -135,7 +198,12 @@ func seedErasmumuOffers(client *http.Client, baseURL string) error {
 			continue
 		}
 
-		if err := postJSON(client, baseURL+"/offers", offer); err != nil {
+		err := postJSON(client, baseURL+"/offers", offer, http.StatusCreated, nil)
+		if errors.Is(err, errAlreadyExists) {
+			fmt.Printf("Skipping duplicate offer: %s (%s, %s)\n", offer.Title, offer.City, offer.Domain)
+			continue
+		}
+		if err != nil {
 			return fmt.Errorf("create offer %q: %w", offer.Title, err)
 		}
 
@@ -150,6 +218,7 @@ func fetchStudents(client *http.Client, url string) ([]Student, error) {
 	if err != nil {
 		return nil, err
 	}
+	authorize(req)
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -170,30 +239,58 @@ func fetchStudents(client *http.Client, url string) ([]Student, error) {
 }
 
 func fetchOffers(client *http.Client, url string) ([]common.Offer, error) {
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, err
-	}
+	var offers []common.Offer
+	cursor := ""
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	for {
+		pageURL := url + "?limit=100"
+		if cursor != "" {
+			pageURL += "&cursor=" + cursor
+		}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, responseError(resp)
-	}
+		req, err := http.NewRequest(http.MethodGet, pageURL, nil)
+		if err != nil {
+			return nil, err
+		}
 
-	var offers []common.Offer
-	if err := json.NewDecoder(resp.Body).Decode(&offers); err != nil {
-		return nil, err
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			err := responseError(resp)
+			resp.Body.Close()
+			return nil, err
+		}
+
+		var page common.OfferPage
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		offers = append(offers, page.Offers...)
+		if page.NextCursor == "" {
+			return offers, nil
+		}
+		cursor = page.NextCursor
 	}
+}
+
+// errAlreadyExists is returned by postJSON when the service rejects the payload as a duplicate.
+var errAlreadyExists = errors.New("already exists")
 
-	return offers, nil
+// authorize authenticates req as the admin once logged in.
+func authorize(req *http.Request) {
+	if bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+bearerToken)
+	}
 }
 
-func postJSON(client *http.Client, url string, payload interface{}) error {
+// postJSON posts payload and decodes the response into out, when not nil; any status but want is an error.
+func postJSON(client *http.Client, url string, payload interface{}, want int, out interface{}) error {
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return err
@@ -204,6 +301,7 @@ func postJSON(client *http.Client, url string, payload interface{}) error {
 		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	authorize(req)
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -211,21 +309,27 @@ func postJSON(client *http.Client, url string, payload interface{}) error {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusCreated {
+	if resp.StatusCode == http.StatusConflict {
+		return errAlreadyExists
+	}
+	if resp.StatusCode != want {
 		return responseError(resp)
 	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
 
 	return nil
 }
 
-func hasStudent(existing []Student, candidate Student) bool {
+func findStudent(existing []Student, candidate Student) (Student, bool) {
 	for _, student := range existing {
 		if strings.EqualFold(student.Name, candidate.Name) && strings.EqualFold(student.Domain, candidate.Domain) {
-			return true
+			return student, true
 		}
 	}
 
-	return false
+	return Student{}, false
 }
 
 func hasOffer(existing []common.Offer, candidate common.Offer) bool {
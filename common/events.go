@@ -5,11 +5,32 @@ const (
 
 	RoutingKeyMI8News           = "mi8.news"
 	RoutingKeyOfferCreated      = "offer.created"
+	RoutingKeyOfferUpdated      = "offer.updated"
+	RoutingKeyOfferClosed       = "offer.closed"
+	RoutingKeyOfferDeleted      = "offer.deleted"
+	RoutingKeyOfferExpired      = "offer.expired"
 	RoutingKeyStudentRegistered = "student.registered"
 
 	QueueMI8News                = "mi8.news"
 	QueueMI8OfferCreated        = "mi8.offer.created"
+	QueueMI8OfferUpdated        = "mi8.offer.updated"
+	QueueMI8OfferClosed         = "mi8.offer.closed"
+	QueueMI8OfferDeleted        = "mi8.offer.deleted"
+	QueueMI8OfferExpired        = "mi8.offer.expired"
 	QueuePolytechOfferCreated   = "polytech.offer.created"
+	QueuePolytechOfferUpdated   = "polytech.offer.updated"
+	QueuePolytechOfferClosed    = "polytech.offer.closed"
+	QueuePolytechOfferDeleted   = "polytech.offer.deleted"
+	QueuePolytechOfferExpired   = "polytech.offer.expired"
 	QueueLaPosteStudentRegister = "laposte.student.registered"
 	QueueLaPosteOfferCreated    = "laposte.offer.created"
+	QueueLaPosteOfferUpdated    = "laposte.offer.updated"
+	QueueLaPosteOfferClosed     = "laposte.offer.closed"
+	QueueLaPosteOfferDeleted    = "laposte.offer.deleted"
+
+	RoutingKeyInternshipStatusChanged   = "internship.status_changed"
+	QueueLaPosteInternshipStatusChanged = "laposte.internship.status_changed"
+
+	// RoutingKeyCityScoreChanged is consumed with ConsumeBroadcastEvents: every Polytech instance drops its cache.
+	RoutingKeyCityScoreChanged = "mi8.city_score_changed"
 )
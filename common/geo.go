@@ -0,0 +1,89 @@
+package common
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// EarthRadiusKm is the mean Earth radius used for great-circle distances.
+const EarthRadiusKm = 6371.0
+
+//go:embed data/cities.csv
+var citiesCSV string
+
+// City is one entry of the bundled city reference dataset.
+type City struct {
+	Name      string  `json:"name"`
+	Country   string  `json:"country"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+var (
+	cities     map[string]City
+	citiesOnce sync.Once
+)
+
+// loadCities parses the bundled dataset on first use.
+func loadCities() map[string]City {
+	citiesOnce.Do(func() {
+		var err error
+		if cities, err = parseCities(citiesCSV); err != nil {
+			log.Fatalf("Invalid bundled city dataset: %v", err)
+		}
+	})
+	return cities
+}
+
+// LookupCity finds a city of the bundled dataset by name, ignoring case and surrounding spaces.
+func LookupCity(name string) (City, bool) {
+	city, ok := loadCities()[cityKey(name)]
+	return city, ok
+}
+
+// Cities returns every city of the bundled dataset.
+func Cities() []City {
+	all := loadCities()
+	list := make([]City, 0, len(all))
+	for _, city := range all {
+		list = append(list, city)
+	}
+	return list
+}
+
+func cityKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// parseCities reads the name,country,latitude,longitude CSV of the dataset.
+func parseCities(data string) (map[string]City, error) {
+	records, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("missing header")
+	}
+
+	parsed := make(map[string]City, len(records)-1)
+	for i, record := range records[1:] {
+		if len(record) != 4 {
+			return nil, fmt.Errorf("line %d: expected 4 fields", i+2)
+		}
+		latitude, err := strconv.ParseFloat(record[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid latitude: %w", i+2, err)
+		}
+		longitude, err := strconv.ParseFloat(record[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid longitude: %w", i+2, err)
+		}
+		parsed[cityKey(record[0])] = City{Name: record[0], Country: record[1], Latitude: latitude, Longitude: longitude}
+	}
+	return parsed, nil
+}
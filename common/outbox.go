@@ -0,0 +1,209 @@
+package common
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	outboxBatchSize     = 100
+	outboxRelayInterval = 2 * time.Second
+
+	// outboxReconnectMinDelay and outboxReconnectMaxDelay bound the exponential backoff between reconnections
+	// of the relay to the broker.
+	outboxReconnectMinDelay = time.Second
+	outboxReconnectMaxDelay = time.Minute
+)
+
+// Outbox stores events in the same transaction as the business rows that caused them;
+// a relay publishes pending rows to RabbitMQ afterwards, so events survive broker outages and restarts.
+type Outbox struct {
+	db    *sql.DB
+	table string
+	wake  chan struct{}
+}
+
+// NewOutbox returns an outbox backed by the given table. Services create it in a migration
+// with the columns id BIGSERIAL, routing_key, payload JSONB, created_at and sent_at.
+func NewOutbox(db *sql.DB, table string) *Outbox {
+	return &Outbox{db: db, table: table, wake: make(chan struct{}, 1)}
+}
+
+// Enqueue records an event inside tx. It is published only once tx commits.
+func (o *Outbox) Enqueue(tx *sql.Tx, routingKey string, event interface{}) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %w", routingKey, err)
+	}
+
+	_, err = tx.Exec(fmt.Sprintf("INSERT INTO %s (routing_key, payload) VALUES ($1, $2)", o.table), routingKey, payload)
+	if err != nil {
+		return fmt.Errorf("failed to store %s event in outbox: %w", routingKey, err)
+	}
+
+	return nil
+}
+
+// Notify wakes the relay up so freshly committed events do not wait for the next tick.
+func (o *Outbox) Notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Relay publishes pending events to the broker at url with publisher confirms, for as long as the process runs.
+// It owns its connection: when the connection or channel drops, it reconnects with exponential backoff and
+// resumes with the rows still pending. A row is marked sent only after the broker confirmed it, so delivery is
+// at least once.
+func (o *Outbox) Relay(url string) {
+	delay := outboxReconnectMinDelay
+	for {
+		connected, err := o.relayConnection(url)
+		if connected {
+			delay = outboxReconnectMinDelay
+		}
+		log.Printf("Outbox %s relay disconnected, reconnecting in %s: %v", o.table, delay, err)
+		time.Sleep(delay)
+
+		delay *= 2
+		if delay > outboxReconnectMaxDelay {
+			delay = outboxReconnectMaxDelay
+		}
+	}
+}
+
+// relayConnection connects to url and relays pending events until the connection or channel closes. connected
+// reports whether the channel was ready, so the caller resets its backoff.
+func (o *Outbox) relayConnection(url string) (connected bool, err error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return false, fmt.Errorf("failed to open channel: %w", err)
+	}
+	defer ch.Close()
+
+	if err := ch.Confirm(false); err != nil {
+		return false, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+	closed := ch.NotifyClose(make(chan *amqp.Error, 1))
+	log.Printf("Outbox %s relay connected", o.table)
+
+	ticker := time.NewTicker(outboxRelayInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			sent, err := o.relayBatch(ch)
+			if err != nil {
+				log.Printf("Outbox %s relay failed: %v", o.table, err)
+				break
+			}
+			if sent < outboxBatchSize {
+				break
+			}
+		}
+
+		select {
+		case amqpErr := <-closed:
+			if amqpErr == nil {
+				return true, errors.New("channel closed")
+			}
+			return true, amqpErr
+		case <-ticker.C:
+		case <-o.wake:
+		}
+	}
+}
+
+// relayBatch publishes up to outboxBatchSize pending events and returns how many were confirmed.
+func (o *Outbox) relayBatch(ch *amqp.Channel) (int, error) {
+	tx, err := o.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.Query(fmt.Sprintf(
+		"SELECT id, routing_key, payload FROM %s WHERE sent_at IS NULL ORDER BY id LIMIT %d FOR UPDATE SKIP LOCKED",
+		o.table, outboxBatchSize,
+	))
+	if err != nil {
+		return 0, fmt.Errorf("failed to query pending events: %w", err)
+	}
+
+	type pendingEvent struct {
+		id         int64
+		routingKey string
+		payload    []byte
+	}
+
+	var pending []pendingEvent
+	for rows.Next() {
+		var event pendingEvent
+		if err := rows.Scan(&event.id, &event.routingKey, &event.payload); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("failed to scan pending event: %w", err)
+		}
+		pending = append(pending, event)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed iterating pending events: %w", err)
+	}
+
+	sent := 0
+	for _, event := range pending {
+		confirmation, err := ch.PublishWithDeferredConfirmWithContext(
+			context.Background(),
+			TopicExchange,
+			event.routingKey,
+			false,
+			false,
+			amqp.Publishing{
+				ContentType:  "application/json",
+				DeliveryMode: amqp.Persistent,
+				MessageId:    o.table + ":" + strconv.FormatInt(event.id, 10),
+				Body:         event.payload,
+			},
+		)
+		if err != nil {
+			err = fmt.Errorf("failed to publish event %d: %w", event.id, err)
+			return sent, o.commitSent(tx, sent, err)
+		}
+		if !confirmation.Wait() {
+			err = fmt.Errorf("broker rejected event %d", event.id)
+			return sent, o.commitSent(tx, sent, err)
+		}
+
+		if _, err := tx.Exec(fmt.Sprintf("UPDATE %s SET sent_at = CURRENT_TIMESTAMP WHERE id = $1", o.table), event.id); err != nil {
+			return sent, fmt.Errorf("failed to mark event %d as sent: %w", event.id, err)
+		}
+		sent++
+	}
+
+	return sent, o.commitSent(tx, sent, nil)
+}
+
+// commitSent keeps the rows already confirmed by the broker marked as sent, then reports cause.
+func (o *Outbox) commitSent(tx *sql.Tx, sent int, cause error) error {
+	if sent > 0 {
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit sent events: %w", err)
+		}
+	}
+	return cause
+}
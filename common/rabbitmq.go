@@ -1,6 +1,8 @@
 package common
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"time"
@@ -8,9 +10,14 @@ import (
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// RabbitMQURL returns the AMQP URL of the broker at host and port.
+func RabbitMQURL(host, port string) string {
+	return fmt.Sprintf("amqp://guest:guest@%s:%s/", host, port)
+}
+
 // InitRabbitMQ opens a RabbitMQ connection/channel with retries and exits on failure.
 func InitRabbitMQ(host, port string) (*amqp.Connection, *amqp.Channel) {
-	addr := fmt.Sprintf("amqp://guest:guest@%s:%s/", host, port)
+	addr := RabbitMQURL(host, port)
 
 	var conn *amqp.Connection
 	var err error
@@ -34,3 +41,78 @@ func InitRabbitMQ(host, port string) (*amqp.Connection, *amqp.Channel) {
 	log.Fatalf("Failed to connect to RabbitMQ after 10 attempts: %v", err)
 	return nil, nil
 }
+
+// PublishEvent publishes event as JSON on the topic exchange, for services without an outbox table.
+// Delivery is best effort: a message lost while RabbitMQ is down is not retried.
+func PublishEvent(ctx context.Context, ch *amqp.Channel, routingKey string, event interface{}) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %w", routingKey, err)
+	}
+
+	err = ch.PublishWithContext(ctx, TopicExchange, routingKey, false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Body:         payload,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish %s event: %w", routingKey, err)
+	}
+	return nil
+}
+
+// ConsumeEvents binds a durable queue to routingKey and feeds every delivery to handle.
+// Messages are acked when handle succeeds and requeued otherwise.
+func ConsumeEvents(ch *amqp.Channel, queueName, routingKey string, handle func(payload []byte) error) {
+	queue, err := ch.QueueDeclare(queueName, true, false, false, false, nil)
+	if err != nil {
+		log.Printf("Failed to declare queue %s: %v", queueName, err)
+		return
+	}
+
+	consumeQueue(ch, queue.Name, routingKey, handle)
+}
+
+// ConsumeBroadcastEvents feeds every routingKey delivery to handle in each instance of a service, through an
+// exclusive queue named by RabbitMQ and deleted with the connection. Events published while the instance is down
+// are not kept for it, so handle should only update state living in the instance, such as caches.
+func ConsumeBroadcastEvents(ch *amqp.Channel, routingKey string, handle func(payload []byte) error) {
+	queue, err := ch.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		log.Printf("Failed to declare broadcast queue for %s: %v", routingKey, err)
+		return
+	}
+
+	consumeQueue(ch, queue.Name, routingKey, handle)
+}
+
+// consumeQueue binds queueName to routingKey, acking the deliveries handle succeeds with and requeuing the others.
+func consumeQueue(ch *amqp.Channel, queueName, routingKey string, handle func(payload []byte) error) {
+	err := ch.QueueBind(queueName, routingKey, TopicExchange, false, nil)
+	if err != nil {
+		log.Printf("Failed to bind queue %s: %v", queueName, err)
+		return
+	}
+
+	deliveries, err := ch.Consume(queueName, "", false, false, false, false, nil)
+	if err != nil {
+		log.Printf("Failed to register consumer on %s: %v", queueName, err)
+		return
+	}
+
+	log.Printf("Subscribed to routing key %s", routingKey)
+
+	for msg := range deliveries {
+		if err := handle(msg.Body); err != nil {
+			log.Printf("Failed to process %s event: %v", routingKey, err)
+			if nackErr := msg.Nack(false, true); nackErr != nil {
+				log.Printf("Failed to nack message: %v", nackErr)
+			}
+			continue
+		}
+
+		if ackErr := msg.Ack(false); ackErr != nil {
+			log.Printf("Failed to ack message: %v", ackErr)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package common
+
+import "strings"
+
+// Requirement is a skill or spoken language an offer asks for; students missing a mandatory one cannot apply.
+type Requirement struct {
+	Name      string `json:"name"`
+	Mandatory bool   `json:"mandatory"`
+}
+
+// NormalizeSkill lowercases a skill or language name and collapses its spaces, so "Go " and "go" match.
+func NormalizeSkill(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// NormalizeRequirements normalizes requirement names, drops empty ones and merges duplicates;
+// a requirement listed twice is mandatory if either entry is.
+func NormalizeRequirements(requirements []Requirement) []Requirement {
+	normalized := make([]Requirement, 0, len(requirements))
+	index := make(map[string]int, len(requirements))
+	for _, requirement := range requirements {
+		name := NormalizeSkill(requirement.Name)
+		if name == "" {
+			continue
+		}
+		if i, ok := index[name]; ok {
+			normalized[i].Mandatory = normalized[i].Mandatory || requirement.Mandatory
+			continue
+		}
+		index[name] = len(normalized)
+		normalized = append(normalized, Requirement{Name: name, Mandatory: requirement.Mandatory})
+	}
+	return normalized
+}
+
+// NormalizeSkills normalizes skill or language names and drops empty and repeated ones.
+func NormalizeSkills(names []string) []string {
+	normalized := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = NormalizeSkill(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		normalized = append(normalized, name)
+	}
+	return normalized
+}
@@ -1,4 +1,4 @@
-//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative proto/common.proto
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative proto/common.proto proto/erasmumu.proto
 package common
 
 type CityScore struct {
@@ -20,6 +20,42 @@ type Offer struct {
 	StartDate string `json:"startDate"`
 	EndDate   string `json:"endDate"`
 	Available bool   `json:"available"`
+	Seats     int    `json:"seats"`
+	SeatsLeft int    `json:"seatsLeft"`
+	// Salary is paid in Currency per PayPeriod (monthly, hourly or total); SalaryEURMonthly is its
+	// monthly EUR equivalent, used to compare offers.
+	Currency         string `json:"currency"`
+	PayPeriod        string `json:"payPeriod"`
+	SalaryEURMonthly int    `json:"salaryEurMonthly"`
+	// EmployerID is nil for offers published before employers existed.
+	EmployerID   *int   `json:"employerId,omitempty"`
+	EmployerName string `json:"employerName,omitempty"`
+	// Skills and Languages list what the offer asks of students, see Requirement.
+	Skills    []Requirement `json:"skills"`
+	Languages []Requirement `json:"languages"`
+	// Country and coordinates come from the city dataset, see LookupCity; they are empty for unknown cities.
+	Country    string   `json:"country,omitempty"`
+	Latitude   *float64 `json:"latitude,omitempty"`
+	Longitude  *float64 `json:"longitude,omitempty"`
+	DistanceKm *float64 `json:"distanceKm,omitempty"`
+}
+
+// Employer is a company publishing offers on Erasmumu.
+type Employer struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Website   string `json:"website,omitempty"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// Reservation holds one seat of an offer; pending reservations lapse at ExpiresAt unless confirmed.
+type Reservation struct {
+	ID        int    `json:"id"`
+	OfferID   int    `json:"offer_id"`
+	Holder    string `json:"holder"`
+	Status    string `json:"status"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+	CreatedAt string `json:"created_at"`
 }
 
 type News struct {
@@ -32,9 +68,87 @@ type News struct {
 }
 
 type OfferCreatedEvent struct {
+	OfferID      int           `json:"offer_id"`
+	Title        string        `json:"title"`
+	Domain       string        `json:"domain"`
+	City         string        `json:"city"`
+	EmployerName string        `json:"employer_name,omitempty"`
+	Skills       []Requirement `json:"skills,omitempty"`
+	Languages    []Requirement `json:"languages,omitempty"`
+	CreatedAt    string        `json:"created_at"`
+}
+
+type OfferUpdatedEvent struct {
+	OfferID      int           `json:"offer_id"`
+	Title        string        `json:"title"`
+	Domain       string        `json:"domain"`
+	City         string        `json:"city"`
+	EmployerName string        `json:"employer_name,omitempty"`
+	Salary       int           `json:"salary"`
+	Currency     string        `json:"currency"`
+	PayPeriod    string        `json:"pay_period"`
+	StartDate    string        `json:"start_date"`
+	EndDate      string        `json:"end_date"`
+	Available    bool          `json:"available"`
+	Skills       []Requirement `json:"skills,omitempty"`
+	Languages    []Requirement `json:"languages,omitempty"`
+	Changes      []FieldChange `json:"changes,omitempty"`
+	UpdatedAt    string        `json:"updated_at"`
+}
+
+// FieldChange records the old and new value of one field changed by an offer mutation.
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+type OfferClosedEvent struct {
+	OfferID  int    `json:"offer_id"`
+	Title    string `json:"title"`
+	Domain   string `json:"domain"`
+	City     string `json:"city"`
+	ClosedAt string `json:"closed_at"`
+}
+
+type OfferDeletedEvent struct {
 	OfferID   int    `json:"offer_id"`
 	Title     string `json:"title"`
 	Domain    string `json:"domain"`
 	City      string `json:"city"`
-	CreatedAt string `json:"created_at"`
+	DeletedAt string `json:"deleted_at"`
+}
+
+type OfferExpiredEvent struct {
+	OfferID   int    `json:"offer_id"`
+	Title     string `json:"title"`
+	Domain    string `json:"domain"`
+	City      string `json:"city"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	ExpiredAt string `json:"expired_at"`
+}
+
+// InternshipStatusChangedEvent is published by Polytech on every internship status transition.
+type InternshipStatusChangedEvent struct {
+	InternshipID int    `json:"internship_id"`
+	StudentID    int    `json:"student_id"`
+	OfferID      int    `json:"offer_id"`
+	From         string `json:"from"`
+	To           string `json:"to"`
+	Reason       string `json:"reason,omitempty"`
+	ChangedAt    string `json:"changed_at"`
+}
+
+// CityScoreChangedEvent is published by MI8 when news changes the scores of a city.
+type CityScoreChangedEvent struct {
+	City      string    `json:"city"`
+	Scores    CityScore `json:"scores"`
+	ChangedAt string    `json:"changed_at"`
+}
+
+// OfferPage is one page of GET /offers results; NextCursor is empty on the last page.
+type OfferPage struct {
+	Offers     []Offer `json:"offers"`
+	NextCursor string  `json:"next_cursor,omitempty"`
 }
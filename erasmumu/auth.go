@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/thomasrubini/polymove/common/auth"
+)
+
+// jwtSecret verifies the tokens issued by Polytech's POST /auth/login, see auth.SecretFromEnv.
+var jwtSecret []byte
+
+// requireRole lets through requests authenticated with one of roles; the claims are stored in the request context.
+func requireRole(fn func(w http.ResponseWriter, r *http.Request) error, roles ...string) func(w http.ResponseWriter, r *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		token, err := auth.BearerToken(r)
+		if err != nil {
+			return unauthorized("%v", err)
+		}
+		claims, err := auth.Verify(jwtSecret, token, time.Now())
+		if err != nil {
+			return unauthorized("%v", err)
+		}
+		if !claims.HasRole(roles...) {
+			return forbidden("%s users may not %s %s", claims.Role, r.Method, r.URL.Path)
+		}
+		return fn(w, r.WithContext(auth.WithClaims(r.Context(), claims)))
+	}
+}
+
+// authorizeEmployer checks that the caller may publish offers for employerID: admins for anyone, employers for
+// themselves. An offer an employer publishes without an employer is attributed to them.
+func authorizeEmployer(ctx context.Context, employerID **int) error {
+	claims, _ := auth.FromContext(ctx)
+	if claims.Role != auth.RoleEmployer {
+		return nil
+	}
+	if *employerID == nil {
+		id := claims.EmployerID
+		*employerID = &id
+		return nil
+	}
+	if **employerID != claims.EmployerID {
+		return forbidden("employer %d may not publish offers for employer %d", claims.EmployerID, **employerID)
+	}
+	return nil
+}
+
+// authorizeOffer checks that the caller may change an existing offer: employers only change their own.
+func authorizeOffer(ctx context.Context, id int) error {
+	claims, _ := auth.FromContext(ctx)
+	if claims.Role != auth.RoleEmployer {
+		return nil
+	}
+
+	var employerID sql.NullInt64
+	err := db.QueryRowContext(ctx, "SELECT employer_id FROM offers WHERE id = $1", id).Scan(&employerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound("offer", id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get offer: %w", err)
+	}
+	if !employerID.Valid || int(employerID.Int64) != claims.EmployerID {
+		return forbidden("offer %d does not belong to employer %d", id, claims.EmployerID)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/thomasrubini/polymove/common"
+)
+
+// Pay periods an offer salary can be expressed in.
+const (
+	payPeriodMonthly = "monthly"
+	payPeriodHourly  = "hourly"
+	payPeriodTotal   = "total"
+)
+
+const (
+	// defaultCurrency is the currency of offers created without one; it is the normalization target.
+	defaultCurrency = "EUR"
+
+	// hoursPerMonth converts hourly salaries: a 35-hour week over 52 weeks, spread on 12 months.
+	hoursPerMonth = 35 * 52 / 12.0
+
+	// daysPerMonth converts salaries paid for the whole internship.
+	daysPerMonth = 365.25 / 12
+)
+
+// currencyCode matches ISO 4217 codes.
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// ExchangeRate is the EUR value of one unit of a currency.
+type ExchangeRate struct {
+	Currency  string  `json:"currency"`
+	EURRate   float64 `json:"eurRate"`
+	UpdatedAt string  `json:"updatedAt"`
+}
+
+// knownPayPeriod returns whether period is a supported pay period.
+func knownPayPeriod(period string) bool {
+	return period == payPeriodMonthly || period == payPeriodHourly || period == payPeriodTotal
+}
+
+// monthlyEUR converts the salary of a validated offer to a monthly EUR amount using rate.
+func monthlyEUR(offer common.Offer, rate float64) int {
+	amount := float64(offer.Salary) * rate
+	switch offer.PayPeriod {
+	case payPeriodHourly:
+		amount *= hoursPerMonth
+	case payPeriodTotal:
+		start, _ := time.Parse("2006-01-02", offer.StartDate)
+		end, _ := time.Parse("2006-01-02", offer.EndDate)
+		months := math.Max(1, end.Sub(start).Hours()/24/daysPerMonth)
+		amount /= months
+	}
+	return int(math.Round(amount))
+}
+
+// normalizeOfferSalary sets SalaryEURMonthly from the current rate of the offer currency inside tx.
+// The rate row is share-locked so a concurrent rate update waits for the offer to be stored.
+func normalizeOfferSalary(tx *sql.Tx, offer *common.Offer) error {
+	var rate float64
+	err := tx.QueryRow("SELECT eur_rate FROM exchange_rates WHERE currency = $1 FOR SHARE", offer.Currency).Scan(&rate)
+	if err == sql.ErrNoRows {
+		return &ValidationError{Fields: []FieldError{{Field: "currency", Message: "has no exchange rate"}}}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get exchange rate: %w", err)
+	}
+
+	offer.SalaryEURMonthly = monthlyEUR(*offer, rate)
+	return nil
+}
+
+// ratedCurrencies returns the currencies that have an exchange rate.
+func ratedCurrencies() (map[string]bool, error) {
+	rows, err := db.Query("SELECT currency FROM exchange_rates")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query exchange rates: %w", err)
+	}
+	defer rows.Close()
+
+	rated := make(map[string]bool)
+	for rows.Next() {
+		var currency string
+		if err := rows.Scan(&currency); err != nil {
+			return nil, fmt.Errorf("failed to scan exchange rate: %w", err)
+		}
+		rated[currency] = true
+	}
+	return rated, rows.Err()
+}
+
+// getExchangeRates handles GET /exchange-rates - Lists the configured exchange rates
+func getExchangeRates(w http.ResponseWriter, r *http.Request) error {
+	rows, err := db.Query("SELECT currency, eur_rate, TO_CHAR(updated_at, 'YYYY-MM-DD\"T\"HH24:MI:SS\"Z\"') FROM exchange_rates ORDER BY currency")
+	if err != nil {
+		return fmt.Errorf("failed to query exchange rates: %w", err)
+	}
+	defer rows.Close()
+
+	rates := []ExchangeRate{}
+	for rows.Next() {
+		var rate ExchangeRate
+		if err := rows.Scan(&rate.Currency, &rate.EURRate, &rate.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to scan exchange rate: %w", err)
+		}
+		rates = append(rates, rate)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed iterating exchange rates: %w", err)
+	}
+
+	return NewResponseWriter(w).JSON(http.StatusOK, rates)
+}
+
+// putExchangeRate handles PUT /exchange-rates/{currency} - Sets a rate and re-normalizes the offers paid in that currency
+func putExchangeRate(w http.ResponseWriter, r *http.Request) error {
+	currency := strings.ToUpper(mux.Vars(r)["currency"])
+	if !currencyCode.MatchString(currency) {
+		return badRequest("invalid currency %q: expected an ISO 4217 code", currency)
+	}
+	if currency == defaultCurrency {
+		return unprocessable("the %s rate is fixed to 1", defaultCurrency)
+	}
+
+	var body struct {
+		EURRate float64 `json:"eurRate"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return badRequest("failed to decode request body: %v", err)
+	}
+	if body.EURRate <= 0 || math.IsInf(body.EURRate, 0) {
+		return &ValidationError{Fields: []FieldError{{Field: "eurRate", Message: "must be a positive number"}}}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rate := ExchangeRate{Currency: currency, EURRate: body.EURRate}
+	err = tx.QueryRow(`
+	INSERT INTO exchange_rates (currency, eur_rate) VALUES ($1, $2)
+	ON CONFLICT (currency) DO UPDATE SET eur_rate = EXCLUDED.eur_rate, updated_at = CURRENT_TIMESTAMP
+	RETURNING TO_CHAR(updated_at, 'YYYY-MM-DD"T"HH24:MI:SS"Z"')`,
+		currency, body.EURRate,
+	).Scan(&rate.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to store exchange rate: %w", err)
+	}
+
+	renormalized, err := renormalizeSalaries(tx, rate)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit exchange rate: %w", err)
+	}
+
+	log.Printf("Exchange rate %s=%g EUR set by %s, %d offers re-normalized", currency, body.EURRate, actorFromRequest(r), renormalized)
+
+	return NewResponseWriter(w).JSON(http.StatusOK, rate)
+}
+
+// renormalizeSalaries recomputes the monthly EUR salary of every offer paid in the rate currency.
+func renormalizeSalaries(tx *sql.Tx, rate ExchangeRate) (int, error) {
+	rows, err := tx.Query("SELECT "+offerColumns+" FROM offers WHERE currency = $1 FOR UPDATE", rate.Currency)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query offers: %w", err)
+	}
+	var offers []common.Offer
+	for rows.Next() {
+		var offer common.Offer
+		if err := scanOffer(rows, &offer); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan offer: %w", err)
+		}
+		offers = append(offers, offer)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed iterating offers: %w", err)
+	}
+
+	for _, offer := range offers {
+		if _, err := tx.Exec("UPDATE offers SET salary_eur_monthly = $1 WHERE id = $2", monthlyEUR(offer, rate.EURRate), offer.ID); err != nil {
+			return 0, fmt.Errorf("failed to re-normalize offer %d: %w", offer.ID, err)
+		}
+	}
+	return len(offers), nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/thomasrubini/polymove/common"
+)
+
+const (
+	// duplicateTitleSimilarity is the minimum title similarity, between 0 and 1, for two offers with the
+	// same city, domain and dates to be duplicates.
+	duplicateTitleSimilarity = 0.85
+
+	// duplicateLockKey serializes duplicate checks with the insertions they guard, see pg_advisory_xact_lock.
+	duplicateLockKey = "offers_duplicates"
+
+	idempotencyKeyHeader = "Idempotency-Key"
+	maxIdempotencyKeyLen = 255
+	idempotencyKeyTTL    = 24 * time.Hour
+)
+
+// findDuplicateOffer returns the existing offer a new one duplicates, if any: same link, or same city, domain
+// and dates with a similar title. It takes a transaction-level lock held until the new offer is committed,
+// so concurrent creations cannot both miss each other.
+func findDuplicateOffer(tx *sql.Tx, offer common.Offer) (*DuplicateOfferError, error) {
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext($1))", duplicateLockKey); err != nil {
+		return nil, fmt.Errorf("failed to take duplicate lock: %w", err)
+	}
+
+	var id int
+	err := tx.QueryRow("SELECT id FROM offers WHERE link = $1 ORDER BY id LIMIT 1", strings.TrimSpace(offer.Link)).Scan(&id)
+	if err == nil {
+		return &DuplicateOfferError{ExistingID: id, Reason: "same link"}, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to look up offers by link: %w", err)
+	}
+
+	rows, err := tx.Query(
+		"SELECT id, title FROM offers WHERE domain = $1 AND start_date = $2 AND end_date = $3 AND LOWER(TRIM(city)) = LOWER(TRIM($4)) ORDER BY id",
+		offer.Domain, offer.StartDate, offer.EndDate, offer.City,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up similar offers: %w", err)
+	}
+	defer rows.Close()
+
+	title := normalizeTitle(offer.Title)
+	for rows.Next() {
+		var existing string
+		if err := rows.Scan(&id, &existing); err != nil {
+			return nil, fmt.Errorf("failed to scan similar offer: %w", err)
+		}
+		if titleSimilarity(title, normalizeTitle(existing)) >= duplicateTitleSimilarity {
+			return &DuplicateOfferError{ExistingID: id, Reason: "similar title, city, domain and dates"}, nil
+		}
+	}
+	return nil, rows.Err()
+}
+
+// normalizeTitle lowercases a title and reduces punctuation and spacing to single spaces.
+func normalizeTitle(title string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// titleSimilarity is 1 minus the Levenshtein distance between a and b relative to the longest of them.
+func titleSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return 1 - float64(previous[len(rb)])/float64(longest)
+}
+
+// idempotentRequest identifies a retried POST /offers by its Idempotency-Key and payload.
+type idempotentRequest struct {
+	Key  string
+	Hash string
+}
+
+// idempotencyFromRequest reads the Idempotency-Key header; ok is false when the request has none.
+// The payload hash covers the offer after defaults, so equivalent bodies match.
+func idempotencyFromRequest(r *http.Request, offer common.Offer) (idempotentRequest, bool, error) {
+	key := strings.TrimSpace(r.Header.Get(idempotencyKeyHeader))
+	if key == "" {
+		return idempotentRequest{}, false, nil
+	}
+	if len(key) > maxIdempotencyKeyLen {
+		return idempotentRequest{}, false, badRequest("%s must be at most %d characters", idempotencyKeyHeader, maxIdempotencyKeyLen)
+	}
+
+	payload, err := json.Marshal(offer)
+	if err != nil {
+		return idempotentRequest{}, false, fmt.Errorf("failed to hash offer: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+	return idempotentRequest{Key: key, Hash: hex.EncodeToString(sum[:])}, true, nil
+}
+
+// replayIdempotentRequest locks the key for the rest of tx and returns the response stored by an earlier request
+// with the same key, or nil for a new key. Reusing a key with another payload is a 422.
+func replayIdempotentRequest(tx *sql.Tx, req idempotentRequest) (json.RawMessage, error) {
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext($1))", "offer_idempotency:"+req.Key); err != nil {
+		return nil, fmt.Errorf("failed to take idempotency lock: %w", err)
+	}
+
+	_, err := tx.Exec("DELETE FROM offer_idempotency_keys WHERE created_at < $1", time.Now().UTC().Add(-idempotencyKeyTTL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to purge idempotency keys: %w", err)
+	}
+
+	var (
+		hash     string
+		response json.RawMessage
+	)
+	err = tx.QueryRow("SELECT request_hash, response FROM offer_idempotency_keys WHERE key = $1", req.Key).Scan(&hash, &response)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	if hash != req.Hash {
+		return nil, unprocessable("%s %q was already used with a different offer", idempotencyKeyHeader, req.Key)
+	}
+	return response, nil
+}
+
+// storeIdempotentResponse records the created offer so retries of the request replay it.
+func storeIdempotentResponse(tx *sql.Tx, req idempotentRequest, offer common.Offer) error {
+	response, err := json.Marshal(offer)
+	if err != nil {
+		return fmt.Errorf("failed to encode offer: %w", err)
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO offer_idempotency_keys (key, request_hash, offer_id, response, created_at) VALUES ($1, $2, $3, $4, $5)",
+		req.Key, req.Hash, offer.ID, string(response), time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store idempotency key: %w", err)
+	}
+	return nil
+}
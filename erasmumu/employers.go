@@ -0,0 +1,315 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+
+	"github.com/thomasrubini/polymove/common"
+)
+
+// employerColumns lists the employers columns in the order expected by scanEmployer.
+const employerColumns = `id, name, website, TO_CHAR(created_at, 'YYYY-MM-DD"T"HH24:MI:SS"Z"')`
+
+// scanEmployer reads one employer selected with employerColumns.
+func scanEmployer(row rowScanner, employer *common.Employer) error {
+	return row.Scan(&employer.ID, &employer.Name, &employer.Website, &employer.CreatedAt)
+}
+
+// employerIDFromRequest parses the {id} route variable of employer routes.
+func employerIDFromRequest(r *http.Request) (int, error) {
+	raw := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(raw)
+	if err != nil || id <= 0 {
+		return 0, badRequest("invalid employer id %q", raw)
+	}
+	return id, nil
+}
+
+// checkEmployer rejects employers that break field rules.
+func checkEmployer(employer common.Employer) error {
+	var problems []FieldError
+	if strings.TrimSpace(employer.Name) == "" {
+		problems = append(problems, FieldError{Field: "name", Message: "is required"})
+	} else if len(employer.Name) > maxOfferTextLength {
+		problems = append(problems, FieldError{Field: "name", Message: "must be at most 255 characters"})
+	}
+	if employer.Website != "" {
+		if website, err := url.Parse(employer.Website); err != nil || (website.Scheme != "http" && website.Scheme != "https") || website.Host == "" {
+			problems = append(problems, FieldError{Field: "website", Message: "must be an absolute http or https URL"})
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Fields: problems}
+	}
+	return nil
+}
+
+// decodeEmployer reads and validates an employer payload.
+func decodeEmployer(r *http.Request) (common.Employer, error) {
+	var employer common.Employer
+	if err := json.NewDecoder(r.Body).Decode(&employer); err != nil {
+		return employer, badRequest("failed to decode request body: %v", err)
+	}
+	employer.Name = strings.TrimSpace(employer.Name)
+	employer.Website = strings.TrimSpace(employer.Website)
+	return employer, checkEmployer(employer)
+}
+
+// employerNameConflict maps the unique index on employer names to a 409.
+func employerNameConflict(err error, name string) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return conflict("an employer named %q already exists", name)
+	}
+	return nil
+}
+
+// lookupOfferEmployer sets the employer name of an offer inside tx. The employer row is share-locked
+// so it cannot be deleted before the offer referencing it is stored.
+func lookupOfferEmployer(tx *sql.Tx, offer *common.Offer) error {
+	offer.EmployerName = ""
+	if offer.EmployerID == nil {
+		return nil
+	}
+
+	err := tx.QueryRow("SELECT name FROM employers WHERE id = $1 FOR SHARE", *offer.EmployerID).Scan(&offer.EmployerName)
+	if err == sql.ErrNoRows {
+		return &ValidationError{Fields: []FieldError{{Field: "employerId", Message: "is not a known employer"}}}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get employer: %w", err)
+	}
+	return nil
+}
+
+// requireEmployer returns a NotFoundError unless the employer exists.
+func requireEmployer(id int) error {
+	var exists bool
+	if err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM employers WHERE id = $1)", id).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to get employer: %w", err)
+	}
+	if !exists {
+		return notFound("employer", id)
+	}
+	return nil
+}
+
+// knownEmployers returns which of ids are existing employers.
+func knownEmployers(ids []int) (map[int]bool, error) {
+	known := make(map[int]bool)
+	if len(ids) == 0 {
+		return known, nil
+	}
+
+	rows, err := db.Query("SELECT id FROM employers WHERE id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query employers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan employer: %w", err)
+		}
+		known[id] = true
+	}
+	return known, rows.Err()
+}
+
+// getEmployers handles GET /employers - Lists every employer
+func getEmployers(w http.ResponseWriter, r *http.Request) error {
+	rows, err := db.Query("SELECT " + employerColumns + " FROM employers ORDER BY id")
+	if err != nil {
+		return fmt.Errorf("failed to query employers: %w", err)
+	}
+	defer rows.Close()
+
+	employers := []common.Employer{}
+	for rows.Next() {
+		var employer common.Employer
+		if err := scanEmployer(rows, &employer); err != nil {
+			return fmt.Errorf("failed to scan employer: %w", err)
+		}
+		employers = append(employers, employer)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed iterating employers: %w", err)
+	}
+
+	return NewResponseWriter(w).JSON(http.StatusOK, employers)
+}
+
+// createEmployer handles POST /employers - Registers a new employer
+func createEmployer(w http.ResponseWriter, r *http.Request) error {
+	employer, err := decodeEmployer(r)
+	if err != nil {
+		return err
+	}
+
+	err = scanEmployer(db.QueryRow(
+		"INSERT INTO employers (name, website) VALUES ($1, $2) RETURNING "+employerColumns,
+		employer.Name, employer.Website,
+	), &employer)
+	if conflictErr := employerNameConflict(err, employer.Name); conflictErr != nil {
+		return conflictErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert employer: %w", err)
+	}
+
+	log.Printf("Created employer id=%d name=%q", employer.ID, employer.Name)
+
+	return NewResponseWriter(w).JSON(http.StatusCreated, employer)
+}
+
+// getEmployerByID handles GET /employers/{id} - Retrieves one employer
+func getEmployerByID(w http.ResponseWriter, r *http.Request) error {
+	id, err := employerIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	var employer common.Employer
+	err = scanEmployer(db.QueryRow("SELECT "+employerColumns+" FROM employers WHERE id = $1", id), &employer)
+	if err == sql.ErrNoRows {
+		return notFound("employer", id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get employer: %w", err)
+	}
+
+	return NewResponseWriter(w).JSON(http.StatusOK, employer)
+}
+
+// updateEmployer handles PUT /employers/{id} - Renames an employer or changes its website
+func updateEmployer(w http.ResponseWriter, r *http.Request) error {
+	id, err := employerIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	employer, err := decodeEmployer(r)
+	if err != nil {
+		return err
+	}
+
+	err = scanEmployer(db.QueryRow(
+		"UPDATE employers SET name = $1, website = $2 WHERE id = $3 RETURNING "+employerColumns,
+		employer.Name, employer.Website, id,
+	), &employer)
+	if err == sql.ErrNoRows {
+		return notFound("employer", id)
+	}
+	if conflictErr := employerNameConflict(err, employer.Name); conflictErr != nil {
+		return conflictErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update employer: %w", err)
+	}
+
+	return NewResponseWriter(w).JSON(http.StatusOK, employer)
+}
+
+// deleteEmployer handles DELETE /employers/{id} - Removes an employer that no longer has offers
+func deleteEmployer(w http.ResponseWriter, r *http.Request) error {
+	id, err := employerIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	err = tx.QueryRow("SELECT id FROM employers WHERE id = $1 FOR UPDATE", id).Scan(&id)
+	if err == sql.ErrNoRows {
+		return notFound("employer", id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get employer: %w", err)
+	}
+
+	var offers int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM offers WHERE employer_id = $1", id).Scan(&offers); err != nil {
+		return fmt.Errorf("failed to count employer offers: %w", err)
+	}
+	if offers > 0 {
+		return conflict("employer %d still has %d offers", id, offers)
+	}
+
+	if _, err := tx.Exec("DELETE FROM employers WHERE id = $1", id); err != nil {
+		return fmt.Errorf("failed to delete employer: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit employer deletion: %w", err)
+	}
+
+	log.Printf("Deleted employer id=%d", id)
+
+	NewResponseWriter(w).NoContent()
+	return nil
+}
+
+// getEmployerOffers handles GET /employers/{id}/offers - Lists the offers of one employer with the GET /offers filters
+func getEmployerOffers(w http.ResponseWriter, r *http.Request) error {
+	id, err := employerIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	if err := requireEmployer(id); err != nil {
+		return err
+	}
+
+	values := r.URL.Query()
+	values.Set("employer_id", strconv.Itoa(id))
+	q, err := parseOfferQuery(values)
+	if err != nil {
+		return err
+	}
+
+	page, err := listOffers(q)
+	if err != nil {
+		return err
+	}
+
+	return NewResponseWriter(w).JSON(http.StatusOK, page)
+}
+
+// createEmployerOffer handles POST /employers/{id}/offers - Publishes an offer on behalf of an employer
+func createEmployerOffer(w http.ResponseWriter, r *http.Request) error {
+	id, err := employerIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	if err := requireEmployer(id); err != nil {
+		return err
+	}
+	employerID := &id
+	if err := authorizeEmployer(r.Context(), &employerID); err != nil {
+		return err
+	}
+
+	var offer common.Offer
+	if err := json.NewDecoder(r.Body).Decode(&offer); err != nil {
+		return badRequest("failed to decode request body: %v", err)
+	}
+	offer.EmployerID = &id
+
+	return publishOffer(w, r, offer)
+}
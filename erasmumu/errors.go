@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/thomasrubini/polymove/common/apierror"
+)
+
+// Handler errors follow the common/apierror model: their kind picks the HTTP and gRPC status, and they carry a
+// stable code. The types below add what Erasmumu responses report beyond the message.
+
+// FieldError describes why one request field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when a well-formed request breaks field rules; it maps to 400.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Is(target error) bool { return target == apierror.ErrValidation }
+
+func (e *ValidationError) ErrorCode() string { return "invalid_fields" }
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Field+": "+field.Message)
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// NotFoundError is returned when the requested resource does not exist; it maps to 404.
+type NotFoundError struct {
+	Resource string
+	ID       interface{}
+}
+
+func (e *NotFoundError) Is(target error) bool { return target == apierror.ErrNotFound }
+
+func (e *NotFoundError) ErrorCode() string {
+	return strings.ReplaceAll(e.Resource, " ", "_") + "_not_found"
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s with id %v not found", e.Resource, e.ID)
+}
+
+// DuplicateOfferError is returned when a new offer matches an existing one; it maps to 409.
+type DuplicateOfferError struct {
+	ExistingID int
+	Reason     string
+}
+
+func (e *DuplicateOfferError) Is(target error) bool { return target == apierror.ErrConflict }
+
+func (e *DuplicateOfferError) ErrorCode() string { return "duplicate_offer" }
+
+func (e *DuplicateOfferError) Error() string {
+	return fmt.Sprintf("offer duplicates offer %d: %s", e.ExistingID, e.Reason)
+}
+
+// badRequest rejects malformed bodies, ids or query parameters with 400.
+func badRequest(format string, args ...interface{}) error {
+	return apierror.Validation("bad_request", format, args...)
+}
+
+// unauthorized rejects a request carrying no valid bearer token with 401.
+func unauthorized(format string, args ...interface{}) error {
+	return apierror.Unauthorized(apierror.CodeUnauthorized, format, args...)
+}
+
+// forbidden rejects a request the authenticated caller may not make with 403.
+func forbidden(format string, args ...interface{}) error {
+	return apierror.Forbidden(apierror.CodeForbidden, format, args...)
+}
+
+func notFound(resource string, id interface{}) error {
+	return &NotFoundError{Resource: resource, ID: id}
+}
+
+// conflict rejects a request clashing with the current state with 409.
+func conflict(format string, args ...interface{}) error {
+	return apierror.Conflict(apierror.CodeConflict, format, args...)
+}
+
+// unprocessable rejects a valid request that cannot be applied with 422.
+func unprocessable(format string, args ...interface{}) error {
+	return apierror.Unprocessable(apierror.CodeUnprocessable, format, args...)
+}
@@ -1,54 +1,94 @@
 package main
 
 import (
-	"encoding/json"
-	"fmt"
+	"database/sql"
 	"time"
 
-	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/thomasrubini/polymove/common"
 )
 
-var rmqConn *amqp.Connection
-var rmqChannel *amqp.Channel
+// outbox holds offer events until the relay has published them.
+var outbox *common.Outbox
 
-// initRabbitMQ initializes a RabbitMQ connection and channel for Erasmumu publishers.
-func initRabbitMQ() {
-	rmqConn, rmqChannel = common.InitRabbitMQ(
-		getEnv("RABBITMQ_HOST", "localhost"),
-		getEnv("RABBITMQ_PORT", "5672"),
-	)
+// rabbitMQURL locates the broker the outbox relay publishes to; the relay connects and reconnects on its own.
+func rabbitMQURL() string {
+	return common.RabbitMQURL(getEnv("RABBITMQ_HOST", "localhost"), getEnv("RABBITMQ_PORT", "5672"))
 }
 
-// publishOfferCreatedEvent publishes an offer.created event for a newly inserted offer.
-func publishOfferCreatedEvent(offer common.Offer) error {
-	event := common.OfferCreatedEvent{
+// enqueueEvent records an event for the outbox relay and for the webhook subscriptions listening to it.
+func enqueueEvent(tx *sql.Tx, routingKey string, event interface{}) error {
+	if err := outbox.Enqueue(tx, routingKey, event); err != nil {
+		return err
+	}
+	return enqueueWebhookDeliveries(tx, routingKey, event)
+}
+
+// enqueueOfferCreatedEvent records an offer.created event for a newly inserted offer.
+func enqueueOfferCreatedEvent(tx *sql.Tx, offer common.Offer) error {
+	return enqueueEvent(tx, common.RoutingKeyOfferCreated, common.OfferCreatedEvent{
+		OfferID:      offer.ID,
+		Title:        offer.Title,
+		Domain:       offer.Domain,
+		City:         offer.City,
+		EmployerName: offer.EmployerName,
+		Skills:       offer.Skills,
+		Languages:    offer.Languages,
+		CreatedAt:    time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// enqueueOfferUpdatedEvent records an offer.updated event with the offer's new state and what changed.
+func enqueueOfferUpdatedEvent(tx *sql.Tx, offer common.Offer, changes []common.FieldChange) error {
+	return enqueueEvent(tx, common.RoutingKeyOfferUpdated, common.OfferUpdatedEvent{
+		OfferID:      offer.ID,
+		Title:        offer.Title,
+		Domain:       offer.Domain,
+		City:         offer.City,
+		EmployerName: offer.EmployerName,
+		Salary:       offer.Salary,
+		Currency:     offer.Currency,
+		PayPeriod:    offer.PayPeriod,
+		StartDate:    offer.StartDate,
+		EndDate:      offer.EndDate,
+		Available:    offer.Available,
+		Skills:       offer.Skills,
+		Languages:    offer.Languages,
+		Changes:      changes,
+		UpdatedAt:    time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// enqueueOfferClosedEvent records an offer.closed event once an offer stops accepting students.
+func enqueueOfferClosedEvent(tx *sql.Tx, offer common.Offer) error {
+	return enqueueEvent(tx, common.RoutingKeyOfferClosed, common.OfferClosedEvent{
+		OfferID:  offer.ID,
+		Title:    offer.Title,
+		Domain:   offer.Domain,
+		City:     offer.City,
+		ClosedAt: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// enqueueOfferDeletedEvent records an offer.deleted event for a removed offer.
+func enqueueOfferDeletedEvent(tx *sql.Tx, offer common.Offer) error {
+	return enqueueEvent(tx, common.RoutingKeyOfferDeleted, common.OfferDeletedEvent{
 		OfferID:   offer.ID,
 		Title:     offer.Title,
 		Domain:    offer.Domain,
 		City:      offer.City,
-		CreatedAt: time.Now().UTC().Format(time.RFC3339),
-	}
-
-	body, err := json.Marshal(event)
-	if err != nil {
-		return fmt.Errorf("failed to marshal offer event: %w", err)
-	}
-
-	err = rmqChannel.Publish(
-		common.TopicExchange,
-		common.RoutingKeyOfferCreated,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType:  "application/json",
-			DeliveryMode: amqp.Persistent,
-			Body:         body,
-		},
-	)
-	if err != nil {
-		return fmt.Errorf("failed to publish event: %w", err)
-	}
+		DeletedAt: time.Now().UTC().Format(time.RFC3339),
+	})
+}
 
-	return nil
+// enqueueOfferExpiredEvent records an offer.expired event for an offer closed by the expiry scheduler.
+func enqueueOfferExpiredEvent(tx *sql.Tx, offer common.Offer) error {
+	return enqueueEvent(tx, common.RoutingKeyOfferExpired, common.OfferExpiredEvent{
+		OfferID:   offer.ID,
+		Title:     offer.Title,
+		Domain:    offer.Domain,
+		City:      offer.City,
+		StartDate: offer.StartDate,
+		EndDate:   offer.EndDate,
+		ExpiredAt: time.Now().UTC().Format(time.RFC3339),
+	})
 }
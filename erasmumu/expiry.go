@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/thomasrubini/polymove/common"
+)
+
+const defaultOfferExpiryInterval = 15 * time.Minute
+
+// offerExpiryInterval reads OFFER_EXPIRY_INTERVAL (a Go duration such as "10m").
+func offerExpiryInterval() time.Duration {
+	raw := getEnv("OFFER_EXPIRY_INTERVAL", "")
+	if raw == "" {
+		return defaultOfferExpiryInterval
+	}
+
+	interval, err := time.ParseDuration(raw)
+	if err != nil || interval <= 0 {
+		log.Printf("Invalid OFFER_EXPIRY_INTERVAL %q, using %s", raw, defaultOfferExpiryInterval)
+		return defaultOfferExpiryInterval
+	}
+	return interval
+}
+
+// runOfferExpiryScheduler closes stale offers at startup and then on every tick.
+func runOfferExpiryScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		expired, err := expireOffers()
+		if err != nil {
+			log.Printf("Offer expiry failed: %v", err)
+		} else if expired > 0 {
+			log.Printf("Expired %d offers", expired)
+		}
+
+		<-ticker.C
+	}
+}
+
+// expireOffers marks available or full offers whose start date has passed or whose end date is reached
+// as unavailable and records one offer.expired event per offer. Concurrent replicas are safe:
+// the UPDATE only returns rows it flipped itself.
+func expireOffers() (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.Query(
+		"UPDATE offers SET available = false, filled = false WHERE (available OR filled) AND (start_date < CURRENT_DATE OR end_date <= CURRENT_DATE) RETURNING " + offerColumns,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire offers: %w", err)
+	}
+
+	var expired []common.Offer
+	for rows.Next() {
+		var offer common.Offer
+		if err := scanOffer(rows, &offer); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("failed to scan offer: %w", err)
+		}
+		expired = append(expired, offer)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed iterating expired offers: %w", err)
+	}
+
+	for _, offer := range expired {
+		changes := []common.FieldChange{{Field: "available", From: true, To: false}}
+		if err := recordOfferRevision(tx, offer.ID, revisionActionExpired, systemActor, changes); err != nil {
+			return 0, err
+		}
+		if err := enqueueOfferExpiredEvent(tx, offer); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit expired offers: %w", err)
+	}
+	if len(expired) > 0 {
+		outbox.Notify()
+	}
+
+	return len(expired), nil
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+
+	"github.com/thomasrubini/polymove/common"
+)
+
+const (
+	feedTitle = "Erasmumu internship offers"
+
+	// feedSize is how many offers a feed lists when the request sets no limit: readers poll feeds and only
+	// show the newest entries, so a feed is one page and ignores cursor.
+	feedSize = 50
+	// defaultPublicURL is the address of Erasmumu in feed links when ERASMUMU_PUBLIC_URL is unset, the one
+	// compose.yml publishes.
+	defaultPublicURL = "http://localhost:8081"
+
+	// icsLineLimit is the maximum iCalendar line length in octets, see RFC 5545 section 3.1.
+	icsLineLimit = 75
+)
+
+// offerTimes is when an offer was first published and last changed, from its revisions.
+type offerTimes struct {
+	Published time.Time
+	Updated   time.Time
+}
+
+// feedOffers lists the offers of a feed with the GET /offers query code; feeds show the newest feedSize offers
+// unless the request picks another sort or limit.
+func feedOffers(r *http.Request) ([]common.Offer, map[int]offerTimes, error) {
+	values := r.URL.Query()
+	if values.Get("sort") == "" {
+		values.Set("sort", "id")
+		values.Set("order", "desc")
+	}
+	if values.Get("limit") == "" {
+		values.Set("limit", strconv.Itoa(feedSize))
+	}
+	values.Del("cursor")
+
+	q, err := parseOfferQuery(values)
+	if err != nil {
+		return nil, nil, err
+	}
+	page, err := listOffers(q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	times, err := offerRevisionTimes(page.Offers)
+	if err != nil {
+		return nil, nil, err
+	}
+	return page.Offers, times, nil
+}
+
+// offerRevisionTimes reads the first and last revision time of each offer; offers without revisions are missing.
+func offerRevisionTimes(offers []common.Offer) (map[int]offerTimes, error) {
+	times := make(map[int]offerTimes, len(offers))
+	if len(offers) == 0 {
+		return times, nil
+	}
+
+	ids := make([]int64, 0, len(offers))
+	for _, offer := range offers {
+		ids = append(ids, int64(offer.ID))
+	}
+
+	rows, err := db.Query(
+		"SELECT offer_id, MIN(changed_at), MAX(changed_at) FROM offer_revisions WHERE offer_id = ANY($1) GROUP BY offer_id",
+		pq.Array(ids),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query offer revisions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id int
+			t  offerTimes
+		)
+		if err := rows.Scan(&id, &t.Published, &t.Updated); err != nil {
+			return nil, fmt.Errorf("failed to scan offer revision times: %w", err)
+		}
+		t.Published, t.Updated = t.Published.UTC(), t.Updated.UTC()
+		times[id] = t
+	}
+	return times, rows.Err()
+}
+
+// timesOf returns the revision times of an offer, falling back to now for offers without history.
+func timesOf(times map[int]offerTimes, id int, now time.Time) offerTimes {
+	if t, ok := times[id]; ok {
+		return t
+	}
+	return offerTimes{Published: now, Updated: now}
+}
+
+// feedBaseURL is the public address of Erasmumu used in feed links, from ERASMUMU_PUBLIC_URL. It never comes from
+// the request: its Host header is chosen by the client, and feeds are cached and shared.
+func feedBaseURL() string {
+	base := getEnv("ERASMUMU_PUBLIC_URL", "")
+	if base == "" {
+		base = defaultPublicURL
+	}
+	return strings.TrimSuffix(base, "/")
+}
+
+// offerSummary is the plain-text description used by every feed format.
+func offerSummary(offer common.Offer) string {
+	var parts []string
+	if offer.EmployerName != "" {
+		parts = append(parts, offer.EmployerName)
+	}
+	parts = append(parts,
+		offer.City,
+		offer.Domain,
+		fmt.Sprintf("%d %s (%s)", offer.Salary, offer.Currency, offer.PayPeriod),
+		fmt.Sprintf("from %s to %s", offer.StartDate, offer.EndDate),
+		fmt.Sprintf("%d of %d seats left", offer.SeatsLeft, offer.Seats),
+	)
+	return strings.Join(parts, " - ")
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID        string       `xml:"id"`
+	Title     string       `xml:"title"`
+	Published string       `xml:"published"`
+	Updated   string       `xml:"updated"`
+	Link      atomLink     `xml:"link"`
+	Summary   string       `xml:"summary"`
+	Category  atomCategory `xml:"category"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// getOffersAtomFeed handles GET /offers/feed.atom - Atom feed of the offers matching the GET /offers filters
+func getOffersAtomFeed(w http.ResponseWriter, r *http.Request) error {
+	offers, times, err := feedOffers(r)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	base := feedBaseURL()
+	feed := atomFeed{
+		ID:      base + "/offers/feed.atom",
+		Title:   feedTitle,
+		Updated: now.Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Href: base + r.URL.RequestURI()},
+			{Rel: "alternate", Href: base + "/offers?" + r.URL.RawQuery},
+		},
+		Author:  atomAuthor{Name: "Erasmumu"},
+		Entries: make([]atomEntry, 0, len(offers)),
+	}
+	for _, offer := range offers {
+		t := timesOf(times, offer.ID, now)
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:        fmt.Sprintf("%s/offers/%d", base, offer.ID),
+			Title:     offer.Title,
+			Published: t.Published.Format(time.RFC3339),
+			Updated:   t.Updated.Format(time.RFC3339),
+			Link:      atomLink{Rel: "alternate", Href: offer.Link},
+			Summary:   offerSummary(offer),
+			Category:  atomCategory{Term: offer.Domain},
+		})
+	}
+
+	return writeXML(w, "application/atom+xml; charset=utf-8", feed)
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	Category    string  `xml:"category"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// getOffersRSSFeed handles GET /offers/feed.rss - RSS 2.0 feed of the offers matching the GET /offers filters
+func getOffersRSSFeed(w http.ResponseWriter, r *http.Request) error {
+	offers, times, err := feedOffers(r)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	base := feedBaseURL()
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         feedTitle,
+			Link:          base + "/offers?" + r.URL.RawQuery,
+			Description:   "Internship offers published on Erasmumu",
+			LastBuildDate: now.Format(time.RFC1123Z),
+			Items:         make([]rssItem, 0, len(offers)),
+		},
+	}
+	for _, offer := range offers {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       offer.Title,
+			Link:        offer.Link,
+			Description: offerSummary(offer),
+			Category:    offer.Domain,
+			GUID:        rssGUID{Value: fmt.Sprintf("%s/offers/%d", base, offer.ID)},
+			PubDate:     timesOf(times, offer.ID, now).Published.Format(time.RFC1123Z),
+		})
+	}
+
+	return writeXML(w, "application/rss+xml; charset=utf-8", feed)
+}
+
+// writeXML writes v as an XML document.
+func writeXML(w http.ResponseWriter, contentType string, v interface{}) error {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode feed: %w", err)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(append([]byte(xml.Header), body...))
+	return err
+}
+
+// getOffersCalendar handles GET /offers/feed.ics - iCalendar export where each offer is an all-day event
+// spanning its StartDate to its EndDate, matching the GET /offers filters
+func getOffersCalendar(w http.ResponseWriter, r *http.Request) error {
+	offers, times, err := feedOffers(r)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	host := "erasmumu"
+	if base, err := url.Parse(feedBaseURL()); err == nil && base.Host != "" {
+		host = base.Host
+	}
+
+	var b strings.Builder
+	line := func(name, value string) {
+		writeICSLine(&b, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//Polymove//Erasmumu//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("X-WR-CALNAME", escapeICSText(feedTitle))
+	for _, offer := range offers {
+		start, startErr := time.Parse("2006-01-02", offer.StartDate)
+		end, endErr := time.Parse("2006-01-02", offer.EndDate)
+		if startErr != nil || endErr != nil {
+			continue
+		}
+
+		line("BEGIN", "VEVENT")
+		line("UID", fmt.Sprintf("offer-%d@%s", offer.ID, host))
+		line("DTSTAMP", timesOf(times, offer.ID, now).Updated.Format("20060102T150405Z"))
+		line("DTSTART;VALUE=DATE", start.Format("20060102"))
+		// All-day events end on the day after the last one.
+		line("DTEND;VALUE=DATE", end.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY", escapeICSText(offer.Title))
+		line("LOCATION", escapeICSText(offer.City))
+		line("DESCRIPTION", escapeICSText(offerSummary(offer)))
+		line("CATEGORIES", escapeICSText(offer.Domain))
+		line("URL", offer.Link)
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="offers.ics"`)
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(b.String()))
+	return err
+}
+
+// escapeICSText escapes an iCalendar TEXT value.
+func escapeICSText(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+}
+
+// writeICSLine writes one content line terminated by CRLF, folding it every icsLineLimit octets
+// without splitting UTF-8 sequences.
+func writeICSLine(b *strings.Builder, line string) {
+	limit := icsLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts toward the limit.
+		limit = icsLineLimit - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/thomasrubini/polymove/common"
+)
+
+// locateOffer sets the country and coordinates of an offer from its city, or clears them for unknown cities.
+func locateOffer(offer *common.Offer) {
+	city, ok := common.LookupCity(offer.City)
+	if !ok {
+		offer.Country, offer.Latitude, offer.Longitude = "", nil, nil
+		return
+	}
+	offer.Country = city.Country
+	offer.Latitude = &city.Latitude
+	offer.Longitude = &city.Longitude
+}
+
+// nullableCountry stores unknown countries as NULL.
+func nullableCountry(country string) interface{} {
+	if country == "" {
+		return nil
+	}
+	return country
+}
+
+// distanceSQL renders the great-circle distance in km, rounded to 100 m, between an offer and the
+// coordinates bound to the latitude and longitude placeholders.
+func distanceSQL(latitude, longitude int) string {
+	return fmt.Sprintf(
+		"ROUND((2 * %[3]g * ASIN(LEAST(1, SQRT(POWER(SIN(RADIANS(latitude - $%[1]d) / 2), 2)"+
+			" + COS(RADIANS($%[1]d)) * COS(RADIANS(latitude)) * POWER(SIN(RADIANS(longitude - $%[2]d) / 2), 2)))))::numeric, 1)::double precision",
+		latitude, longitude, common.EarthRadiusKm,
+	)
+}
+
+// backfillOfferLocations locates offers stored before coordinates existed or whose city was added to the dataset since.
+func backfillOfferLocations() {
+	located := 0
+	for _, city := range common.Cities() {
+		result, err := db.Exec(
+			"UPDATE offers SET country = $1, latitude = $2, longitude = $3 WHERE LOWER(TRIM(city)) = LOWER($4) AND latitude IS NULL",
+			city.Country, city.Latitude, city.Longitude, city.Name,
+		)
+		if err != nil {
+			log.Printf("Failed to locate offers in %s: %v", city.Name, err)
+			return
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			located += int(n)
+		}
+	}
+	if located > 0 {
+		log.Printf("Located %d offers from the city dataset", located)
+	}
+}
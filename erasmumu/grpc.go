@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/lib/pq"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/thomasrubini/polymove/common"
+	"github.com/thomasrubini/polymove/common/apierror"
+	"github.com/thomasrubini/polymove/common/proto"
+)
+
+// maxBatchOfferIDs bounds GetOffers requests like the page size bounds GET /offers.
+const maxBatchOfferIDs = maxOffersPageSize
+
+// grpcServer serves offers over gRPC with the same queries as the REST handlers.
+type grpcServer struct {
+	proto.UnimplementedErasmumuServiceServer
+}
+
+// serveGRPC runs the ErasmumuService on addr until the listener fails.
+func serveGRPC(addr string) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("Failed to listen for gRPC: %v", err)
+	}
+
+	s := grpc.NewServer()
+	proto.RegisterErasmumuServiceServer(s, &grpcServer{})
+
+	log.Printf("gRPC server starting on %s", addr)
+	if err := s.Serve(lis); err != nil {
+		log.Fatalf("Failed to serve gRPC: %v", err)
+	}
+}
+
+// grpcError maps the typed errors of the REST handlers onto gRPC status codes.
+func grpcError(err error) error {
+	switch apierror.Status(err) {
+	case http.StatusBadRequest:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case http.StatusNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case http.StatusConflict, http.StatusUnprocessableEntity:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		log.Printf("gRPC error: %v", err)
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+// toProtoOffer converts an offer to its gRPC representation.
+func toProtoOffer(offer common.Offer) *proto.Offer {
+	return &proto.Offer{
+		Id:               int32(offer.ID),
+		Title:            offer.Title,
+		Link:             offer.Link,
+		City:             offer.City,
+		Domain:           offer.Domain,
+		Salary:           int32(offer.Salary),
+		StartDate:        offer.StartDate,
+		EndDate:          offer.EndDate,
+		Available:        offer.Available,
+		Seats:            int32(offer.Seats),
+		SeatsLeft:        int32(offer.SeatsLeft),
+		Country:          offer.Country,
+		Latitude:         offer.Latitude,
+		Longitude:        offer.Longitude,
+		DistanceKm:       offer.DistanceKm,
+		Currency:         offer.Currency,
+		PayPeriod:        offer.PayPeriod,
+		SalaryEurMonthly: int32(offer.SalaryEURMonthly),
+		EmployerId:       optionalInt32(offer.EmployerID),
+		EmployerName:     offer.EmployerName,
+		Skills:           toProtoRequirements(offer.Skills),
+		Languages:        toProtoRequirements(offer.Languages),
+	}
+}
+
+// toProtoRequirements converts a requirement list to its gRPC representation.
+func toProtoRequirements(requirements []common.Requirement) []*proto.Requirement {
+	out := make([]*proto.Requirement, 0, len(requirements))
+	for _, requirement := range requirements {
+		out = append(out, &proto.Requirement{Name: requirement.Name, Mandatory: requirement.Mandatory})
+	}
+	return out
+}
+
+// optionalInt32 converts an optional int to its gRPC representation.
+func optionalInt32(value *int) *int32 {
+	if value == nil {
+		return nil
+	}
+	v := int32(*value)
+	return &v
+}
+
+// GetOffer returns one offer by ID.
+func (s *grpcServer) GetOffer(ctx context.Context, req *proto.GetOfferRequest) (*proto.Offer, error) {
+	if req.Id <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid offer id %d", req.Id)
+	}
+
+	var offer common.Offer
+	err := scanOffer(db.QueryRowContext(ctx, "SELECT "+offerColumns+" FROM offers WHERE id = $1", req.Id), &offer)
+	if err == sql.ErrNoRows {
+		return nil, grpcError(notFound("offer", req.Id))
+	}
+	if err != nil {
+		return nil, grpcError(fmt.Errorf("failed to get offer: %w", err))
+	}
+
+	return toProtoOffer(offer), nil
+}
+
+// ListOffers returns one page of offers; filters, sorting and cursors behave like GET /offers.
+func (s *grpcServer) ListOffers(ctx context.Context, req *proto.ListOffersRequest) (*proto.ListOffersResponse, error) {
+	q, err := parseOfferQuery(listOffersValues(req))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	page, err := listOffers(q)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &proto.ListOffersResponse{
+		Offers:     make([]*proto.Offer, 0, len(page.Offers)),
+		NextCursor: page.NextCursor,
+	}
+	for _, offer := range page.Offers {
+		resp.Offers = append(resp.Offers, toProtoOffer(offer))
+	}
+	return resp, nil
+}
+
+// listOffersValues renders a ListOffersRequest as GET /offers query parameters, so both APIs share parseOfferQuery.
+func listOffersValues(req *proto.ListOffersRequest) url.Values {
+	values := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+
+	set("city", req.City)
+	set("domain", req.Domain)
+	set("start_after", req.StartAfter)
+	set("start_before", req.StartBefore)
+	set("end_after", req.EndAfter)
+	set("end_before", req.EndBefore)
+	set("q", req.Q)
+	set("sort", req.Sort)
+	set("order", req.Order)
+	set("cursor", req.Cursor)
+	set("near", req.Near)
+	if req.RadiusKm != 0 {
+		values.Set("radius_km", strconv.FormatFloat(req.RadiusKm, 'f', -1, 64))
+	}
+	if req.EmployerId != nil {
+		values.Set("employer_id", strconv.Itoa(int(*req.EmployerId)))
+	}
+	if req.MinSalary != nil {
+		values.Set("min_salary", strconv.Itoa(int(*req.MinSalary)))
+	}
+	if req.MaxSalary != nil {
+		values.Set("max_salary", strconv.Itoa(int(*req.MaxSalary)))
+	}
+	if req.Available != nil {
+		values.Set("available", strconv.FormatBool(*req.Available))
+	}
+	if req.Limit != 0 {
+		values.Set("limit", strconv.Itoa(int(req.Limit)))
+	}
+	return values
+}
+
+// GetOffers returns several offers by ID in one round trip.
+func (s *grpcServer) GetOffers(ctx context.Context, req *proto.GetOffersRequest) (*proto.GetOffersResponse, error) {
+	if len(req.Ids) > maxBatchOfferIDs {
+		return nil, status.Errorf(codes.InvalidArgument, "too many ids: %d (maximum %d)", len(req.Ids), maxBatchOfferIDs)
+	}
+
+	ids := make([]int64, 0, len(req.Ids))
+	for _, id := range req.Ids {
+		ids = append(ids, int64(id))
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT "+offerColumns+" FROM offers WHERE id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return nil, grpcError(fmt.Errorf("failed to query offers: %w", err))
+	}
+	defer rows.Close()
+
+	found := make(map[int32]*proto.Offer, len(req.Ids))
+	for rows.Next() {
+		var offer common.Offer
+		if err := scanOffer(rows, &offer); err != nil {
+			return nil, grpcError(fmt.Errorf("failed to scan offer: %w", err))
+		}
+		found[int32(offer.ID)] = toProtoOffer(offer)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, grpcError(fmt.Errorf("failed iterating offers: %w", err))
+	}
+
+	resp := &proto.GetOffersResponse{Offers: make([]*proto.Offer, 0, len(found))}
+	for _, id := range req.Ids {
+		if offer, ok := found[id]; ok {
+			resp.Offers = append(resp.Offers, offer)
+		} else {
+			resp.MissingIds = append(resp.MissingIds, id)
+		}
+	}
+	return resp, nil
+}
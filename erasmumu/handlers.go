@@ -6,79 +6,531 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 
 	"github.com/thomasrubini/polymove/common"
 )
 
-// getOffers handles GET /offers - Lists all offers, optionally filtered by city
-func getOffers(w http.ResponseWriter, r *http.Request) error {
-	city := r.URL.Query().Get("city")
+// offerColumns lists the offers columns in the order expected by scanOffer.
+const offerColumns = "id, title, link, city, domain, salary, TO_CHAR(start_date, 'YYYY-MM-DD'), TO_CHAR(end_date, 'YYYY-MM-DD'), available, seats, seats - seats_reserved, currency, pay_period, salary_eur_monthly, employer_id, " +
+	"COALESCE((SELECT name FROM employers WHERE employers.id = offers.employer_id), ''), COALESCE(country, ''), latitude, longitude, skills, languages"
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// offerFields returns the scan destinations matching offerColumns.
+func offerFields(offer *common.Offer) []interface{} {
+	return []interface{}{&offer.ID, &offer.Title, &offer.Link, &offer.City, &offer.Domain, &offer.Salary, &offer.StartDate, &offer.EndDate, &offer.Available, &offer.Seats, &offer.SeatsLeft, &offer.Currency, &offer.PayPeriod, &offer.SalaryEURMonthly, &offer.EmployerID, &offer.EmployerName, &offer.Country, &offer.Latitude, &offer.Longitude,
+		requirementsColumn{&offer.Skills}, requirementsColumn{&offer.Languages}}
+}
+
+// scanOffer reads one offer selected with offerColumns.
+func scanOffer(row rowScanner, offer *common.Offer) error {
+	return row.Scan(offerFields(offer)...)
+}
+
+// OfferPatch is the payload for partial offer updates; nil fields are left untouched.
+type OfferPatch struct {
+	Title      *string `json:"title"`
+	Link       *string `json:"link"`
+	City       *string `json:"city"`
+	Domain     *string `json:"domain"`
+	Salary     *int    `json:"salary"`
+	Currency   *string `json:"currency"`
+	PayPeriod  *string `json:"payPeriod"`
+	StartDate  *string `json:"startDate"`
+	EndDate    *string `json:"endDate"`
+	Available  *bool   `json:"available"`
+	Seats      *int    `json:"seats"`
+	EmployerID *int    `json:"employerId"`
+	// Skills and Languages replace the whole lists.
+	Skills    *[]common.Requirement `json:"skills"`
+	Languages *[]common.Requirement `json:"languages"`
+
+	// ClearEmployer unlinks the offer from its employer when EmployerID is nil; only full replacements set it.
+	ClearEmployer bool `json:"-"`
+}
+
+// fullOfferPatch turns a complete offer into a patch overwriting every field.
+func fullOfferPatch(offer common.Offer) OfferPatch {
+	return OfferPatch{
+		Title:      &offer.Title,
+		Link:       &offer.Link,
+		City:       &offer.City,
+		Domain:     &offer.Domain,
+		Salary:     &offer.Salary,
+		Currency:   &offer.Currency,
+		PayPeriod:  &offer.PayPeriod,
+		StartDate:  &offer.StartDate,
+		EndDate:    &offer.EndDate,
+		Available:  &offer.Available,
+		Seats:      &offer.Seats,
+		EmployerID: offer.EmployerID,
+		Skills:     &offer.Skills,
+		Languages:  &offer.Languages,
 
-	var query string
-	var args []interface{}
+		ClearEmployer: offer.EmployerID == nil,
+	}
+}
+
+// apply copies the fields set in the patch onto offer.
+func (p OfferPatch) apply(offer *common.Offer) {
+	if p.Title != nil {
+		offer.Title = *p.Title
+	}
+	if p.Link != nil {
+		offer.Link = *p.Link
+	}
+	if p.City != nil {
+		offer.City = *p.City
+	}
+	if p.Domain != nil {
+		offer.Domain = *p.Domain
+	}
+	if p.Salary != nil {
+		offer.Salary = *p.Salary
+	}
+	if p.Currency != nil {
+		offer.Currency = strings.ToUpper(strings.TrimSpace(*p.Currency))
+	}
+	if p.PayPeriod != nil {
+		offer.PayPeriod = *p.PayPeriod
+	}
+	if p.StartDate != nil {
+		offer.StartDate = *p.StartDate
+	}
+	if p.EndDate != nil {
+		offer.EndDate = *p.EndDate
+	}
+	if p.Available != nil {
+		offer.Available = *p.Available
+	}
+	if p.Seats != nil {
+		offer.Seats = *p.Seats
+	}
+	if p.EmployerID != nil || p.ClearEmployer {
+		offer.EmployerID = p.EmployerID
+	}
+	if p.Skills != nil {
+		offer.Skills = common.NormalizeRequirements(*p.Skills)
+	}
+	if p.Languages != nil {
+		offer.Languages = common.NormalizeRequirements(*p.Languages)
+	}
+}
 
-	if city != "" {
-		query = "SELECT id, title, link, city, domain, salary, TO_CHAR(start_date, 'YYYY-MM-DD'), TO_CHAR(end_date, 'YYYY-MM-DD'), available FROM offers WHERE city = $1"
-		args = append(args, city)
-	} else {
-		query = "SELECT id, title, link, city, domain, salary, TO_CHAR(start_date, 'YYYY-MM-DD'), TO_CHAR(end_date, 'YYYY-MM-DD'), available FROM offers"
+// offerIDFromRequest parses the {id} route variable.
+func offerIDFromRequest(r *http.Request) (int, error) {
+	raw := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(raw)
+	if err != nil || id <= 0 {
+		return 0, badRequest("invalid offer id %q", raw)
 	}
+	return id, nil
+}
 
-	rows, err := db.Query(query, args...)
+// getOffers handles GET /offers - Lists offers matching the query filters, one page at a time
+func getOffers(w http.ResponseWriter, r *http.Request) error {
+	q, err := parseOfferQuery(r.URL.Query())
 	if err != nil {
-		return fmt.Errorf("failed to query offers: %w", err)
+		return err
 	}
-	defer rows.Close()
 
-	var offers []common.Offer
-	for rows.Next() {
-		var offer common.Offer
-		if err := rows.Scan(&offer.ID, &offer.Title, &offer.Link, &offer.City, &offer.Domain, &offer.Salary, &offer.StartDate, &offer.EndDate, &offer.Available); err != nil {
-			return fmt.Errorf("failed to scan offer: %w", err)
-		}
-		offers = append(offers, offer)
+	page, err := listOffers(q)
+	if err != nil {
+		return err
 	}
 
-	return NewResponseWriter(w).JSON(http.StatusOK, offers)
+	return NewResponseWriter(w).JSON(http.StatusOK, page)
 }
 
 // createOffer handles POST /offers - Creates a new Erasmus offer
 func createOffer(w http.ResponseWriter, r *http.Request) error {
 	var offer common.Offer
 	if err := json.NewDecoder(r.Body).Decode(&offer); err != nil {
-		return fmt.Errorf("failed to decode request body: %w", err)
+		return badRequest("failed to decode request body: %v", err)
+	}
+	if err := authorizeEmployer(r.Context(), &offer.EmployerID); err != nil {
+		return err
 	}
 
-	query := "INSERT INTO offers (title, link, city, domain, salary, start_date, end_date, available) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id"
-	if err := db.QueryRow(query, offer.Title, offer.Link, offer.City, offer.Domain, offer.Salary, offer.StartDate, offer.EndDate, offer.Available).Scan(&offer.ID); err != nil {
-		return fmt.Errorf("failed to insert offer: %w", err)
+	return publishOffer(w, r, offer)
+}
+
+// publishOffer validates and stores a decoded offer, records its revision and offer.created, and writes it.
+// Duplicates of existing offers are rejected; requests retried with the same Idempotency-Key replay the first response.
+func publishOffer(w http.ResponseWriter, r *http.Request, offer common.Offer) error {
+	applyOfferDefaults(&offer)
+
+	if err := checkOffer(offer); err != nil {
+		return err
+	}
+
+	idempotency, idempotent, err := idempotencyFromRequest(r, offer)
+	if err != nil {
+		return err
 	}
 
-	if err := publishOfferCreatedEvent(offer); err != nil {
-		return fmt.Errorf("failed to publish offer.created event: %w", err)
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if idempotent {
+		response, err := replayIdempotentRequest(tx, idempotency)
+		if err != nil {
+			return err
+		}
+		if response != nil {
+			w.Header().Set("Idempotent-Replayed", "true")
+			return NewResponseWriter(w).JSON(http.StatusCreated, response)
+		}
 	}
 
+	duplicate, err := findDuplicateOffer(tx, offer)
+	if err != nil {
+		return err
+	}
+	if duplicate != nil {
+		return duplicate
+	}
+
+	if err := insertOffer(tx, &offer); err != nil {
+		return err
+	}
+
+	if err := recordOfferRevision(tx, offer.ID, revisionActionCreated, actorFromRequest(r), creationChanges(offer)); err != nil {
+		return err
+	}
+
+	if err := enqueueOfferCreatedEvent(tx, offer); err != nil {
+		return err
+	}
+
+	if idempotent {
+		if err := storeIdempotentResponse(tx, idempotency, offer); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit offer: %w", err)
+	}
+	outbox.Notify()
+
 	log.Printf("Created offer id=%d title=%q domain=%s city=%s", offer.ID, offer.Title, offer.Domain, offer.City)
 
 	return NewResponseWriter(w).JSON(http.StatusCreated, offer)
 }
 
+// insertOffer locates, normalizes and stores a new offer inside tx and sets its ID; every seat starts free.
+func insertOffer(tx *sql.Tx, offer *common.Offer) error {
+	locateOffer(offer)
+	if err := normalizeOfferSalary(tx, offer); err != nil {
+		return err
+	}
+	if err := lookupOfferEmployer(tx, offer); err != nil {
+		return err
+	}
+	query := `
+	INSERT INTO offers (title, link, city, domain, salary, start_date, end_date, available, seats,
+		currency, pay_period, salary_eur_monthly, employer_id, country, latitude, longitude, skills, languages)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+	RETURNING id`
+	err := tx.QueryRow(query,
+		offer.Title, offer.Link, offer.City, offer.Domain, offer.Salary, offer.StartDate, offer.EndDate, offer.Available, offer.Seats,
+		offer.Currency, offer.PayPeriod, offer.SalaryEURMonthly, offer.EmployerID,
+		nullableCountry(offer.Country), offer.Latitude, offer.Longitude,
+		requirementsValue(offer.Skills), requirementsValue(offer.Languages),
+	).Scan(&offer.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert offer: %w", err)
+	}
+	offer.SeatsLeft = offer.Seats
+	return nil
+}
+
 // getOfferByID handles GET /offers/{id} - Retrieves a specific offer by ID
 func getOfferByID(w http.ResponseWriter, r *http.Request) error {
-	vars := mux.Vars(r)
-	id := vars["id"]
+	id, err := offerIDFromRequest(r)
+	if err != nil {
+		return err
+	}
 
 	var offer common.Offer
-	query := "SELECT id, title, link, city, domain, salary, TO_CHAR(start_date, 'YYYY-MM-DD'), TO_CHAR(end_date, 'YYYY-MM-DD'), available FROM offers WHERE id = $1"
-	err := db.QueryRow(query, id).Scan(&offer.ID, &offer.Title, &offer.Link, &offer.City, &offer.Domain, &offer.Salary, &offer.StartDate, &offer.EndDate, &offer.Available)
+	query := "SELECT " + offerColumns + " FROM offers WHERE id = $1"
+	err = scanOffer(db.QueryRow(query, id), &offer)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("offer with id %s not found", id)
+			return notFound("offer", id)
 		}
 		return fmt.Errorf("failed to get offer: %w", err)
 	}
 
 	return NewResponseWriter(w).JSON(http.StatusOK, offer)
 }
+
+// replaceOffer handles PUT /offers/{id} - Overwrites every field of an offer
+func replaceOffer(w http.ResponseWriter, r *http.Request) error {
+	id, err := offerIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	if err := authorizeOffer(r.Context(), id); err != nil {
+		return err
+	}
+
+	var offer common.Offer
+	if err := json.NewDecoder(r.Body).Decode(&offer); err != nil {
+		return badRequest("failed to decode request body: %v", err)
+	}
+	if err := authorizeEmployer(r.Context(), &offer.EmployerID); err != nil {
+		return err
+	}
+	applyOfferDefaults(&offer)
+
+	return applyOfferPatch(w, id, actorFromRequest(r), fullOfferPatch(offer))
+}
+
+// patchOffer handles PATCH /offers/{id} - Updates only the fields present in the body
+func patchOffer(w http.ResponseWriter, r *http.Request) error {
+	id, err := offerIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	if err := authorizeOffer(r.Context(), id); err != nil {
+		return err
+	}
+
+	var patch OfferPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		return badRequest("failed to decode request body: %v", err)
+	}
+	if patch.EmployerID != nil {
+		if err := authorizeEmployer(r.Context(), &patch.EmployerID); err != nil {
+			return err
+		}
+	}
+
+	return applyOfferPatch(w, id, actorFromRequest(r), patch)
+}
+
+// applyOfferPatch validates and stores a patch on behalf of actor, records the revision and offer.updated
+// with the field-level diff, and writes the updated offer.
+func applyOfferPatch(w http.ResponseWriter, id int, actor string, patch OfferPatch) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	current, filled, err := lockOffer(tx, id)
+	if err != nil {
+		return err
+	}
+
+	offer := current
+	patch.apply(&offer)
+
+	if err := checkOffer(offer); err != nil {
+		return err
+	}
+
+	reserved := current.Seats - current.SeatsLeft
+	if offer.Seats < reserved {
+		return unprocessable("offer %d has %d reserved seats: seats cannot be lowered to %d", id, reserved, offer.Seats)
+	}
+
+	today := time.Now().UTC().Format("2006-01-02")
+	if offer.Available != current.Available {
+		// An explicit open or close overrides closing on capacity.
+		filled = false
+	} else if filled && offer.Seats > reserved && offer.StartDate >= today && offer.EndDate > today {
+		// New seats reopen an offer that was only closed because it was full.
+		offer.Available, filled = true, false
+	}
+	if offer.Available && !current.Available {
+		if offer.StartDate < today {
+			return unprocessable("offer %d cannot be reopened: its start date %s has passed", id, offer.StartDate)
+		}
+		if offer.EndDate <= today {
+			return unprocessable("offer %d cannot be reopened: its end date %s has been reached", id, offer.EndDate)
+		}
+		if reserved >= offer.Seats {
+			return unprocessable("offer %d cannot be reopened: all %d seats are reserved", id, offer.Seats)
+		}
+	}
+
+	locateOffer(&offer)
+	if err := normalizeOfferSalary(tx, &offer); err != nil {
+		return err
+	}
+	if err := lookupOfferEmployer(tx, &offer); err != nil {
+		return err
+	}
+
+	query := `
+	UPDATE offers SET title = $1, link = $2, city = $3, domain = $4, salary = $5,
+		start_date = $6, end_date = $7, available = $8, seats = $9, filled = $10,
+		currency = $11, pay_period = $12, salary_eur_monthly = $13, employer_id = $14,
+		country = $15, latitude = $16, longitude = $17, skills = $18, languages = $19
+	WHERE id = $20
+	RETURNING ` + offerColumns
+
+	err = scanOffer(tx.QueryRow(query,
+		offer.Title, offer.Link, offer.City, offer.Domain, offer.Salary,
+		offer.StartDate, offer.EndDate, offer.Available, offer.Seats, filled,
+		offer.Currency, offer.PayPeriod, offer.SalaryEURMonthly, offer.EmployerID,
+		nullableCountry(offer.Country), offer.Latitude, offer.Longitude,
+		requirementsValue(offer.Skills), requirementsValue(offer.Languages), id,
+	), &offer)
+	if err != nil {
+		return fmt.Errorf("failed to update offer: %w", err)
+	}
+
+	changes := diffOffers(current, offer)
+	if len(changes) == 0 {
+		// Nothing changed: no revision, no event.
+		return NewResponseWriter(w).JSON(http.StatusOK, offer)
+	}
+
+	if err := recordOfferRevision(tx, offer.ID, revisionActionUpdated, actor, changes); err != nil {
+		return err
+	}
+
+	if err := enqueueOfferUpdatedEvent(tx, offer, changes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit offer update: %w", err)
+	}
+	outbox.Notify()
+
+	log.Printf("Updated offer id=%d title=%q available=%t", offer.ID, offer.Title, offer.Available)
+
+	return NewResponseWriter(w).JSON(http.StatusOK, offer)
+}
+
+// closeOffer handles POST /offers/{id}/close - Marks an offer as no longer available
+func closeOffer(w http.ResponseWriter, r *http.Request) error {
+	id, err := offerIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+	if err := authorizeOffer(r.Context(), id); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var offer common.Offer
+	query := "UPDATE offers SET available = false, filled = false WHERE id = $1 AND (available OR filled) RETURNING " + offerColumns
+	err = scanOffer(tx.QueryRow(query, id), &offer)
+	if err == sql.ErrNoRows {
+		// Either the offer does not exist or it is already closed: only the latter is fine.
+		err = scanOffer(tx.QueryRow("SELECT "+offerColumns+" FROM offers WHERE id = $1", id), &offer)
+		if err == sql.ErrNoRows {
+			return notFound("offer", id)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get offer: %w", err)
+		}
+		return NewResponseWriter(w).JSON(http.StatusOK, offer)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to close offer: %w", err)
+	}
+
+	changes := []common.FieldChange{{Field: "available", From: true, To: false}}
+	if err := recordOfferRevision(tx, offer.ID, revisionActionClosed, actorFromRequest(r), changes); err != nil {
+		return err
+	}
+
+	if err := enqueueOfferClosedEvent(tx, offer); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit offer closing: %w", err)
+	}
+	outbox.Notify()
+
+	log.Printf("Closed offer id=%d title=%q", offer.ID, offer.Title)
+
+	return NewResponseWriter(w).JSON(http.StatusOK, offer)
+}
+
+// deleteOffer handles DELETE /offers/{id} - Removes an offer; offers with active reservations are kept
+func deleteOffer(w http.ResponseWriter, r *http.Request) error {
+	id, err := offerIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+	if err := authorizeOffer(r.Context(), id); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, _, err := lockOffer(tx, id); err != nil {
+		return err
+	}
+
+	// Partners holding a seat must release it first; the others only keep history and go with the offer.
+	var active int
+	err = tx.QueryRow(
+		"SELECT COUNT(*) FROM offer_reservations WHERE offer_id = $1 AND (status = $2 OR (status = $3 AND expires_at > CURRENT_TIMESTAMP))",
+		id, reservationStatusConfirmed, reservationStatusPending,
+	).Scan(&active)
+	if err != nil {
+		return fmt.Errorf("failed to count offer reservations: %w", err)
+	}
+	if active > 0 {
+		return conflict("offer %d has %d active reservations: release them before deleting it", id, active)
+	}
+	if _, err := tx.Exec("DELETE FROM offer_reservations WHERE offer_id = $1", id); err != nil {
+		return fmt.Errorf("failed to delete offer reservations: %w", err)
+	}
+
+	var offer common.Offer
+	err = scanOffer(tx.QueryRow("DELETE FROM offers WHERE id = $1 RETURNING "+offerColumns, id), &offer)
+	if err != nil {
+		return fmt.Errorf("failed to delete offer: %w", err)
+	}
+
+	if err := recordOfferRevision(tx, offer.ID, revisionActionDeleted, actorFromRequest(r), nil); err != nil {
+		return err
+	}
+
+	if err := enqueueOfferDeletedEvent(tx, offer); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit offer deletion: %w", err)
+	}
+	outbox.Notify()
+
+	log.Printf("Deleted offer id=%d title=%q", offer.ID, offer.Title)
+
+	NewResponseWriter(w).NoContent()
+	return nil
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/thomasrubini/polymove/common"
+)
+
+const (
+	maxImportBodyBytes = 5 << 20
+	maxImportRows      = 1000
+
+	importModeAtomic  = "atomic"
+	importModePartial = "partial"
+)
+
+// OfferImportRow reports what happened to one imported row; Row is 1-based and ignores the CSV header.
+// Duplicate rows report the ID of the offer they repeat.
+type OfferImportRow struct {
+	Row        int          `json:"row"`
+	Status     string       `json:"status"`
+	ID         int          `json:"id,omitempty"`
+	ExistingID int          `json:"existingId,omitempty"`
+	Errors     []FieldError `json:"errors,omitempty"`
+}
+
+// OfferImportReport is the response of POST /offers/import.
+type OfferImportReport struct {
+	Mode    string           `json:"mode"`
+	Created int              `json:"created"`
+	Failed  int              `json:"failed"`
+	Rows    []OfferImportRow `json:"rows"`
+}
+
+// importCandidate is a parsed row along with the problems that prevent storing it.
+type importCandidate struct {
+	offer    common.Offer
+	problems []FieldError
+}
+
+// importOffers handles POST /offers/import - Creates offers in bulk from CSV or a JSON array.
+// In the default atomic mode any invalid row aborts the import; with ?mode=partial invalid rows are skipped,
+// and each row is stored under its own savepoint so one the database rejects does not undo the others.
+func importOffers(w http.ResponseWriter, r *http.Request) error {
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = importModeAtomic
+	}
+	if mode != importModeAtomic && mode != importModePartial {
+		return badRequest("invalid mode %q: expected %s or %s", mode, importModeAtomic, importModePartial)
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportBodyBytes)
+
+	var (
+		candidates []importCandidate
+		err        error
+	)
+	if isCSVRequest(r) {
+		candidates, err = parseCSVOffers(body)
+	} else {
+		candidates, err = parseJSONOffers(body)
+	}
+	if err != nil {
+		return err
+	}
+	if len(candidates) > maxImportRows {
+		return badRequest("too many rows: %d (maximum %d)", len(candidates), maxImportRows)
+	}
+
+	if err := checkImportReferences(candidates); err != nil {
+		return err
+	}
+
+	report := OfferImportReport{Mode: mode, Rows: make([]OfferImportRow, len(candidates))}
+	for i, candidate := range candidates {
+		report.Rows[i] = OfferImportRow{Row: i + 1, Status: "pending"}
+		if len(candidate.problems) > 0 {
+			report.Rows[i].Status = "invalid"
+			report.Rows[i].Errors = candidate.problems
+			report.Failed++
+		}
+	}
+
+	if report.Failed > 0 && mode == importModeAtomic {
+		for i := range report.Rows {
+			if report.Rows[i].Status == "pending" {
+				report.Rows[i].Status = "not_imported"
+			}
+		}
+		return NewResponseWriter(w).JSON(http.StatusUnprocessableEntity, report)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	actor := actorFromRequest(r)
+	for i := range candidates {
+		if report.Rows[i].Status != "pending" {
+			continue
+		}
+
+		offer := candidates[i].offer
+		duplicate, err := findDuplicateOffer(tx, offer)
+		if err != nil {
+			return fmt.Errorf("row %d: %w", i+1, err)
+		}
+		if duplicate != nil {
+			report.Rows[i].Status = "duplicate"
+			report.Rows[i].ExistingID = duplicate.ExistingID
+			report.Rows[i].Errors = []FieldError{{Field: "offer", Message: duplicate.Error()}}
+			report.Failed++
+			continue
+		}
+
+		if mode == importModePartial {
+			if _, err := tx.Exec("SAVEPOINT import_row"); err != nil {
+				return fmt.Errorf("row %d: failed to create savepoint: %w", i+1, err)
+			}
+		}
+		if err := storeImportedOffer(tx, &offer, actor); err != nil {
+			if mode == importModeAtomic {
+				return fmt.Errorf("row %d: %w", i+1, err)
+			}
+			if _, rollbackErr := tx.Exec("ROLLBACK TO SAVEPOINT import_row"); rollbackErr != nil {
+				return fmt.Errorf("row %d: failed to roll back to savepoint: %w", i+1, rollbackErr)
+			}
+			log.Printf("Import row %d not stored: %v", i+1, err)
+			report.Rows[i].Status = "failed"
+			report.Rows[i].Errors = []FieldError{{Field: "offer", Message: "could not be stored"}}
+			report.Failed++
+			continue
+		}
+
+		report.Rows[i].Status = "created"
+		report.Rows[i].ID = offer.ID
+		report.Created++
+	}
+
+	if report.Failed > 0 && mode == importModeAtomic {
+		// Duplicates are only found once earlier rows are inserted: roll them back too.
+		for i := range report.Rows {
+			if report.Rows[i].Status == "created" {
+				report.Rows[i].Status, report.Rows[i].ID = "not_imported", 0
+			}
+		}
+		report.Created = 0
+		return NewResponseWriter(w).JSON(http.StatusConflict, report)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit import: %w", err)
+	}
+	outbox.Notify()
+
+	log.Printf("Imported offers mode=%s created=%d failed=%d", mode, report.Created, report.Failed)
+
+	status := http.StatusCreated
+	if report.Created == 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	return NewResponseWriter(w).JSON(status, report)
+}
+
+// storeImportedOffer inserts one imported offer with its creation revision and offer.created event.
+func storeImportedOffer(tx *sql.Tx, offer *common.Offer, actor string) error {
+	if err := insertOffer(tx, offer); err != nil {
+		return err
+	}
+	if err := recordOfferRevision(tx, offer.ID, revisionActionCreated, actor, creationChanges(*offer)); err != nil {
+		return err
+	}
+	return enqueueOfferCreatedEvent(tx, *offer)
+}
+
+// isCSVRequest tells whether the import body is CSV, from ?format= or the Content-Type header.
+func isCSVRequest(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return strings.EqualFold(format, "csv")
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return mediaType == "text/csv" || mediaType == "application/csv"
+}
+
+// parseJSONOffers reads a JSON array of offers. Like CSV rows, offers without available are available.
+func parseJSONOffers(body io.Reader) ([]importCandidate, error) {
+	var rows []json.RawMessage
+	if err := json.NewDecoder(body).Decode(&rows); err != nil {
+		return nil, badRequest("failed to decode request body: %v", err)
+	}
+
+	candidates := make([]importCandidate, 0, len(rows))
+	for _, row := range rows {
+		offer := common.Offer{Available: true}
+		if err := json.Unmarshal(row, &offer); err != nil {
+			candidates = append(candidates, importCandidate{problems: []FieldError{{Field: "row", Message: fmt.Sprintf("is not a valid offer: %v", err)}}})
+			continue
+		}
+		applyOfferDefaults(&offer)
+		candidates = append(candidates, importCandidate{offer: offer, problems: validateOffer(offer)})
+	}
+	return candidates, nil
+}
+
+// parseCSVOffers reads offers from CSV with a header row naming the common.Offer JSON fields.
+// The available and seats columns are optional and default to true and one seat.
+func parseCSVOffers(body io.Reader) ([]importCandidate, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, badRequest("failed to read csv header: %v", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[normalizeCSVColumn(name)] = i
+	}
+	for _, required := range []string{"title", "link", "city", "domain", "salary", "startdate", "enddate"} {
+		if _, ok := columns[required]; !ok {
+			return nil, badRequest("csv header is missing column %q", required)
+		}
+	}
+
+	var candidates []importCandidate
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount) {
+				candidates = append(candidates, importCandidate{problems: []FieldError{{Field: "row", Message: "has the wrong number of fields"}}})
+				continue
+			}
+			return nil, badRequest("failed to read csv: %v", err)
+		}
+
+		field := func(name string) string {
+			index, ok := columns[name]
+			if !ok || index >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[index])
+		}
+
+		offer := common.Offer{
+			Title:     field("title"),
+			Link:      field("link"),
+			City:      field("city"),
+			Domain:    field("domain"),
+			Currency:  field("currency"),
+			PayPeriod: field("payperiod"),
+			StartDate: field("startdate"),
+			EndDate:   field("enddate"),
+			Available: true,
+			Seats:     defaultOfferSeats,
+		}
+
+		var problems []FieldError
+		if salary, err := strconv.Atoi(field("salary")); err != nil {
+			problems = append(problems, FieldError{Field: "salary", Message: "must be an integer"})
+		} else {
+			offer.Salary = salary
+		}
+		if raw := field("available"); raw != "" {
+			available, err := strconv.ParseBool(raw)
+			if err != nil {
+				problems = append(problems, FieldError{Field: "available", Message: "must be true or false"})
+			}
+			offer.Available = available
+		}
+		if raw := field("skills"); raw != "" {
+			offer.Skills = parseRequirementList(raw)
+		}
+		if raw := field("languages"); raw != "" {
+			offer.Languages = parseRequirementList(raw)
+		}
+		if raw := field("employerid"); raw != "" {
+			employerID, err := strconv.Atoi(raw)
+			if err != nil {
+				problems = append(problems, FieldError{Field: "employerId", Message: "must be an integer"})
+			} else {
+				offer.EmployerID = &employerID
+			}
+		}
+		if raw := field("seats"); raw != "" {
+			seats, err := strconv.Atoi(raw)
+			if err != nil {
+				problems = append(problems, FieldError{Field: "seats", Message: "must be an integer"})
+			} else {
+				offer.Seats = seats
+			}
+		}
+
+		applyOfferDefaults(&offer)
+		candidates = append(candidates, importCandidate{offer: offer, problems: append(problems, validateOffer(offer)...)})
+	}
+
+	return candidates, nil
+}
+
+// checkImportReferences flags candidates paid in a currency without exchange rate or naming an unknown employer.
+func checkImportReferences(candidates []importCandidate) error {
+	rated, err := ratedCurrencies()
+	if err != nil {
+		return err
+	}
+
+	var employerIDs []int
+	for _, candidate := range candidates {
+		if candidate.offer.EmployerID != nil {
+			employerIDs = append(employerIDs, *candidate.offer.EmployerID)
+		}
+	}
+	employers, err := knownEmployers(employerIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range candidates {
+		if currency := candidates[i].offer.Currency; currencyCode.MatchString(currency) && !rated[currency] {
+			candidates[i].problems = append(candidates[i].problems, FieldError{Field: "currency", Message: "has no exchange rate"})
+		}
+		if id := candidates[i].offer.EmployerID; id != nil && !employers[*id] {
+			candidates[i].problems = append(candidates[i].problems, FieldError{Field: "employerId", Message: "is not a known employer"})
+		}
+	}
+	return nil
+}
+
+// normalizeCSVColumn lets headers use startDate, start_date or "Start Date" interchangeably.
+func normalizeCSVColumn(name string) string {
+	return strings.NewReplacer("_", "", " ", "", "-", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -11,11 +13,18 @@ import (
 
 	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
+
+	"github.com/thomasrubini/polymove/common"
+	"github.com/thomasrubini/polymove/common/apierror"
+	"github.com/thomasrubini/polymove/common/auth"
 )
 
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
+	Error      string       `json:"error"`
+	Code       string       `json:"code"`
+	Message    string       `json:"message"`
+	Fields     []FieldError `json:"fields,omitempty"`
+	ExistingID int          `json:"existingId,omitempty"`
 }
 
 var db *sql.DB
@@ -40,11 +49,27 @@ func (rw *ResponseWriter) EncodeJSON(data interface{}) error {
 }
 
 func (rw *ResponseWriter) EncodeError(statusCode int, err error) error {
-	rw.WriteHeader(statusCode)
-	return json.NewEncoder(rw).Encode(ErrorResponse{
+	response := ErrorResponse{
 		Error:   http.StatusText(statusCode),
+		Code:    apierror.Code(err),
 		Message: err.Error(),
-	})
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		response.Message = "validation failed"
+		response.Fields = validationErr.Fields
+	}
+
+	var duplicateErr *DuplicateOfferError
+	if errors.As(err, &duplicateErr) {
+		response.ExistingID = duplicateErr.ExistingID
+		rw.Header().Set("Location", fmt.Sprintf("/offers/%d", duplicateErr.ExistingID))
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(statusCode)
+	return json.NewEncoder(rw).Encode(response)
 }
 
 func (rw *ResponseWriter) NoContent() {
@@ -60,8 +85,9 @@ func errorHandler(fn func(w http.ResponseWriter, r *http.Request) error) http.Ha
 	return func(w http.ResponseWriter, r *http.Request) {
 		rw := NewResponseWriter(w)
 		if err := fn(w, r); err != nil {
-			log.Printf("Error: %v", err)
-			if err2 := rw.EncodeError(http.StatusInternalServerError, err); err2 != nil {
+			statusCode := apierror.Status(err)
+			log.Printf("Error (%d): %v", statusCode, err)
+			if err2 := rw.EncodeError(statusCode, err); err2 != nil {
 				log.Printf("Failed to send error to user: %v", err2)
 			}
 		}
@@ -80,15 +106,60 @@ func main() {
 	log.SetOutput(os.Stdout)
 
 	initDB()
-	initRabbitMQ()
-	defer rmqChannel.Close()
-	defer rmqConn.Close()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := common.RunMigrateCommand(context.Background(), newMigrator(), os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	migrateDB()
+	backfillOfferLocations()
+
+	secret, err := auth.SecretFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	jwtSecret = secret
+
+	go outbox.Relay(rabbitMQURL())
+	go runOfferExpiryScheduler(offerExpiryInterval())
+	go runReservationSweeper(reservationSweepInterval)
+	go runWebhookDispatcher(webhookDispatchInterval)
+	go serveGRPC(":" + getEnv("ERASMUMU_GRPC_PORT", "9091"))
 
 	router := mux.NewRouter()
 	router.Use(loggingMiddleware)
 	router.HandleFunc("/offers", errorHandler(getOffers)).Methods(http.MethodGet)
-	router.HandleFunc("/offers", errorHandler(createOffer)).Methods(http.MethodPost)
+	router.HandleFunc("/offers", errorHandler(requireRole(createOffer, auth.RoleEmployer, auth.RoleAdmin))).Methods(http.MethodPost)
+	router.HandleFunc("/offers/import", errorHandler(requireRole(importOffers, auth.RoleAdmin))).Methods(http.MethodPost)
+	router.HandleFunc("/offers/feed.atom", errorHandler(getOffersAtomFeed)).Methods(http.MethodGet)
+	router.HandleFunc("/offers/feed.rss", errorHandler(getOffersRSSFeed)).Methods(http.MethodGet)
+	router.HandleFunc("/offers/feed.ics", errorHandler(getOffersCalendar)).Methods(http.MethodGet)
 	router.HandleFunc("/offers/{id}", errorHandler(getOfferByID)).Methods(http.MethodGet)
+	router.HandleFunc("/offers/{id}", errorHandler(requireRole(replaceOffer, auth.RoleEmployer, auth.RoleAdmin))).Methods(http.MethodPut)
+	router.HandleFunc("/offers/{id}", errorHandler(requireRole(patchOffer, auth.RoleEmployer, auth.RoleAdmin))).Methods(http.MethodPatch)
+	router.HandleFunc("/offers/{id}", errorHandler(requireRole(deleteOffer, auth.RoleEmployer, auth.RoleAdmin))).Methods(http.MethodDelete)
+	router.HandleFunc("/offers/{id}/close", errorHandler(requireRole(closeOffer, auth.RoleEmployer, auth.RoleAdmin))).Methods(http.MethodPost)
+	router.HandleFunc("/offers/{id}/history", errorHandler(getOfferHistory)).Methods(http.MethodGet)
+	router.HandleFunc("/offers/{id}/reservations", errorHandler(requireRole(reserveSeat, auth.RoleService, auth.RoleAdmin))).Methods(http.MethodPost)
+	router.HandleFunc("/reservations/{id}", errorHandler(requireRole(getReservation, auth.RoleService, auth.RoleAdmin))).Methods(http.MethodGet)
+	router.HandleFunc("/reservations/{id}/confirm", errorHandler(requireRole(confirmReservation, auth.RoleService, auth.RoleAdmin))).Methods(http.MethodPost)
+	router.HandleFunc("/reservations/{id}/release", errorHandler(requireRole(releaseReservation, auth.RoleService, auth.RoleAdmin))).Methods(http.MethodPost)
+	router.HandleFunc("/employers", errorHandler(getEmployers)).Methods(http.MethodGet)
+	router.HandleFunc("/employers", errorHandler(requireRole(createEmployer, auth.RoleAdmin))).Methods(http.MethodPost)
+	router.HandleFunc("/employers/{id}", errorHandler(getEmployerByID)).Methods(http.MethodGet)
+	router.HandleFunc("/employers/{id}", errorHandler(requireRole(updateEmployer, auth.RoleAdmin))).Methods(http.MethodPut)
+	router.HandleFunc("/employers/{id}", errorHandler(requireRole(deleteEmployer, auth.RoleAdmin))).Methods(http.MethodDelete)
+	router.HandleFunc("/employers/{id}/offers", errorHandler(getEmployerOffers)).Methods(http.MethodGet)
+	router.HandleFunc("/employers/{id}/offers", errorHandler(requireRole(createEmployerOffer, auth.RoleEmployer, auth.RoleAdmin))).Methods(http.MethodPost)
+	router.HandleFunc("/exchange-rates", errorHandler(getExchangeRates)).Methods(http.MethodGet)
+	router.HandleFunc("/exchange-rates/{currency}", errorHandler(requireRole(putExchangeRate, auth.RoleAdmin))).Methods(http.MethodPut)
+	router.HandleFunc("/webhooks", errorHandler(requireRole(getWebhooks, auth.RoleAdmin))).Methods(http.MethodGet)
+	router.HandleFunc("/webhooks", errorHandler(requireRole(createWebhook, auth.RoleAdmin))).Methods(http.MethodPost)
+	router.HandleFunc("/webhooks/{id}", errorHandler(requireRole(getWebhookByID, auth.RoleAdmin))).Methods(http.MethodGet)
+	router.HandleFunc("/webhooks/{id}", errorHandler(requireRole(updateWebhook, auth.RoleAdmin))).Methods(http.MethodPut)
+	router.HandleFunc("/webhooks/{id}", errorHandler(requireRole(deleteWebhook, auth.RoleAdmin))).Methods(http.MethodDelete)
+	router.HandleFunc("/webhooks/{id}/deliveries", errorHandler(requireRole(getWebhookDeliveries, auth.RoleAdmin))).Methods(http.MethodGet)
 
 	log.Println("Server starting on :8081")
 	log.Fatal(http.ListenAndServe(":8081", router))
@@ -122,7 +193,7 @@ func initDB() {
 		log.Fatal(err)
 	}
 
-	createTable()
+	outbox = common.NewOutbox(db, "erasmumu_outbox")
 }
 
 func getEnv(key, defaultValue string) string {
@@ -131,23 +202,3 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
-
-func createTable() {
-	query := `
-	CREATE TABLE IF NOT EXISTS offers (
-		id SERIAL PRIMARY KEY,
-		title VARCHAR(255) NOT NULL,
-		link TEXT NOT NULL,
-		city VARCHAR(255) NOT NULL,
-		domain VARCHAR(255) NOT NULL,
-		salary INTEGER NOT NULL,
-		start_date DATE NOT NULL,
-		end_date DATE NOT NULL,
-		available BOOLEAN NOT NULL DEFAULT true
-	);
-	`
-	_, err := db.Exec(query)
-	if err != nil {
-		log.Fatal(err)
-	}
-}
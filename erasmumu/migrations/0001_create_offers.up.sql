@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS offers (
+	id SERIAL PRIMARY KEY,
+	title VARCHAR(255) NOT NULL,
+	link TEXT NOT NULL,
+	city VARCHAR(255) NOT NULL,
+	domain VARCHAR(255) NOT NULL,
+	salary INTEGER NOT NULL,
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	available BOOLEAN NOT NULL DEFAULT true
+);
@@ -0,0 +1,11 @@
+-- No foreign key: the history of a deleted offer is kept.
+CREATE TABLE IF NOT EXISTS offer_revisions (
+	id SERIAL PRIMARY KEY,
+	offer_id INTEGER NOT NULL,
+	revision INTEGER NOT NULL,
+	action VARCHAR(32) NOT NULL,
+	changed_by VARCHAR(255) NOT NULL,
+	changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	changes JSONB NOT NULL DEFAULT '[]',
+	UNIQUE(offer_id, revision)
+);
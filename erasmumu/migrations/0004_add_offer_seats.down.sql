@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS offer_reservations;
+
+ALTER TABLE offers
+	DROP COLUMN IF EXISTS filled,
+	DROP COLUMN IF EXISTS seats_reserved,
+	DROP COLUMN IF EXISTS seats;
@@ -0,0 +1,15 @@
+-- seats_reserved counts pending and confirmed reservations; filled marks offers closed because they are full.
+ALTER TABLE offers
+	ADD COLUMN IF NOT EXISTS seats INTEGER NOT NULL DEFAULT 1,
+	ADD COLUMN IF NOT EXISTS seats_reserved INTEGER NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS filled BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS offer_reservations (
+	id SERIAL PRIMARY KEY,
+	offer_id INTEGER NOT NULL REFERENCES offers(id) ON DELETE CASCADE,
+	holder VARCHAR(255) NOT NULL,
+	status VARCHAR(16) NOT NULL,
+	expires_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS offer_reservations_pending_idx ON offer_reservations (expires_at) WHERE status = 'pending';
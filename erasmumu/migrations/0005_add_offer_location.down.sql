@@ -0,0 +1,4 @@
+ALTER TABLE offers
+	DROP COLUMN IF EXISTS longitude,
+	DROP COLUMN IF EXISTS latitude,
+	DROP COLUMN IF EXISTS country;
@@ -0,0 +1,5 @@
+-- Filled from the bundled city dataset; NULL for cities it does not know.
+ALTER TABLE offers
+	ADD COLUMN IF NOT EXISTS country VARCHAR(2),
+	ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION,
+	ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
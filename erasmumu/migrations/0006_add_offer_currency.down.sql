@@ -0,0 +1,6 @@
+ALTER TABLE offers
+	DROP COLUMN IF EXISTS salary_eur_monthly,
+	DROP COLUMN IF EXISTS pay_period,
+	DROP COLUMN IF EXISTS currency;
+
+DROP TABLE IF EXISTS exchange_rates;
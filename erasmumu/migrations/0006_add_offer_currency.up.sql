@@ -0,0 +1,30 @@
+-- EUR value of one unit of each currency, maintained through PUT /exchange-rates/{currency}.
+CREATE TABLE IF NOT EXISTS exchange_rates (
+	currency VARCHAR(3) PRIMARY KEY,
+	eur_rate DOUBLE PRECISION NOT NULL CHECK (eur_rate > 0),
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO exchange_rates (currency, eur_rate) VALUES
+	('EUR', 1),
+	('GBP', 1.17),
+	('CHF', 1.05),
+	('SEK', 0.087),
+	('NOK', 0.086),
+	('DKK', 0.134),
+	('PLN', 0.23),
+	('CZK', 0.04),
+	('HUF', 0.0025),
+	('RON', 0.2),
+	('USD', 0.92)
+ON CONFLICT (currency) DO NOTHING;
+
+-- Existing salaries were monthly EUR amounts.
+ALTER TABLE offers
+	ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'EUR' REFERENCES exchange_rates(currency),
+	ADD COLUMN IF NOT EXISTS pay_period VARCHAR(16) NOT NULL DEFAULT 'monthly',
+	ADD COLUMN IF NOT EXISTS salary_eur_monthly INTEGER;
+
+UPDATE offers SET salary_eur_monthly = salary WHERE salary_eur_monthly IS NULL;
+
+ALTER TABLE offers ALTER COLUMN salary_eur_monthly SET NOT NULL;
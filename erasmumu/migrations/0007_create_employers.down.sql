@@ -0,0 +1,3 @@
+ALTER TABLE offers DROP COLUMN IF EXISTS employer_id;
+
+DROP TABLE IF EXISTS employers;
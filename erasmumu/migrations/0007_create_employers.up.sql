@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS employers (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	website TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS employers_name_idx ON employers (LOWER(name));
+
+-- Offers published before employers existed keep a NULL employer.
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS employer_id INTEGER REFERENCES employers(id);
+CREATE INDEX IF NOT EXISTS offers_employer_id_idx ON offers (employer_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS offers_duplicate_idx;
+DROP INDEX IF EXISTS offers_link_idx;
+
+DROP TABLE IF EXISTS offer_idempotency_keys;
@@ -0,0 +1,12 @@
+-- Responses of POST /offers requests sent with an Idempotency-Key, replayed when the request is retried.
+CREATE TABLE IF NOT EXISTS offer_idempotency_keys (
+	key VARCHAR(255) PRIMARY KEY,
+	request_hash VARCHAR(64) NOT NULL,
+	offer_id INTEGER NOT NULL,
+	response JSONB NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Duplicate detection looks offers up by link and by city, domain and dates.
+CREATE INDEX IF NOT EXISTS offers_link_idx ON offers (link);
+CREATE INDEX IF NOT EXISTS offers_duplicate_idx ON offers (domain, start_date, end_date);
@@ -0,0 +1,3 @@
+ALTER TABLE offers
+	DROP COLUMN IF EXISTS languages,
+	DROP COLUMN IF EXISTS skills;
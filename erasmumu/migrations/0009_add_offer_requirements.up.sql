@@ -0,0 +1,4 @@
+-- Lists of {"name", "mandatory"} requirements, see common.Requirement.
+ALTER TABLE offers
+	ADD COLUMN IF NOT EXISTS skills JSONB NOT NULL DEFAULT '[]',
+	ADD COLUMN IF NOT EXISTS languages JSONB NOT NULL DEFAULT '[]';
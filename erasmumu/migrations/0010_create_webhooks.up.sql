@@ -0,0 +1,28 @@
+-- Partner endpoints notified of offer events over HTTP, see webhooks.go.
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+	id SERIAL PRIMARY KEY,
+	url TEXT NOT NULL,
+	event_types TEXT[] NOT NULL,
+	secret TEXT NOT NULL,
+	enabled BOOLEAN NOT NULL DEFAULT true,
+	consecutive_failures INTEGER NOT NULL DEFAULT 0,
+	disabled_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One row per event and subscription, written with the event and kept as the delivery log.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id BIGSERIAL PRIMARY KEY,
+	subscription_id INTEGER NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+	event_type VARCHAR(64) NOT NULL,
+	payload JSONB NOT NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	last_status_code INTEGER,
+	last_error TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	delivered_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_subscription_idx ON webhook_deliveries (subscription_id, id);
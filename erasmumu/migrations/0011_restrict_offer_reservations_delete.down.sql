@@ -0,0 +1,3 @@
+ALTER TABLE offer_reservations DROP CONSTRAINT IF EXISTS offer_reservations_offer_id_fkey;
+ALTER TABLE offer_reservations ADD CONSTRAINT offer_reservations_offer_id_fkey
+	FOREIGN KEY (offer_id) REFERENCES offers(id) ON DELETE CASCADE;
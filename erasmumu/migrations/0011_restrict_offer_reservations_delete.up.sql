@@ -0,0 +1,5 @@
+-- Deleting an offer must not silently drop the seats partners hold on it: deleteOffer refuses while reservations
+-- are active and removes the others itself.
+ALTER TABLE offer_reservations DROP CONSTRAINT IF EXISTS offer_reservations_offer_id_fkey;
+ALTER TABLE offer_reservations ADD CONSTRAINT offer_reservations_offer_id_fkey
+	FOREIGN KEY (offer_id) REFERENCES offers(id) ON DELETE RESTRICT;
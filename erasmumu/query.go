@@ -0,0 +1,339 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thomasrubini/polymove/common"
+)
+
+const (
+	defaultOffersPageSize = 20
+	maxOffersPageSize     = 100
+)
+
+// offerSortColumns maps the sort query parameter to a column and the cast used for cursor values.
+// Salaries sort, like the min_salary and max_salary filters, on their monthly EUR equivalent.
+var offerSortColumns = map[string]struct {
+	column string
+	cast   string
+}{
+	"id":         {"id", "integer"},
+	"title":      {"title", "text"},
+	"salary":     {"salary_eur_monthly", "integer"},
+	"start_date": {"start_date", "date"},
+	"end_date":   {"end_date", "date"},
+	// The distance column depends on the near city, see buildSQL.
+	"distance": {"", "double precision"},
+}
+
+// offerQuery holds the filters, ordering and page position accepted by GET /offers.
+type offerQuery struct {
+	City        string
+	Domain      string
+	EmployerID  *int
+	MinSalary   *int
+	MaxSalary   *int
+	StartAfter  string
+	StartBefore string
+	EndAfter    string
+	EndBefore   string
+	Available   *bool
+	Search      string
+	Near        *common.City
+	RadiusKm    float64
+	Sort        string
+	Desc        bool
+	Limit       int
+	Cursor      *offerCursor
+}
+
+// offerCursor is the position after the last offer of a page.
+type offerCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// parseOfferQuery reads GET /offers query parameters.
+func parseOfferQuery(values url.Values) (offerQuery, error) {
+	q := offerQuery{
+		City:   values.Get("city"),
+		Domain: values.Get("domain"),
+		Search: strings.TrimSpace(values.Get("q")),
+		Sort:   "id",
+		Limit:  defaultOffersPageSize,
+	}
+
+	var err error
+	if q.EmployerID, err = parseOptionalInt(values, "employer_id"); err != nil {
+		return q, err
+	}
+	if q.MinSalary, err = parseOptionalInt(values, "min_salary"); err != nil {
+		return q, err
+	}
+	if q.MaxSalary, err = parseOptionalInt(values, "max_salary"); err != nil {
+		return q, err
+	}
+
+	dates := []struct {
+		key    string
+		target *string
+	}{
+		{"start_after", &q.StartAfter},
+		{"start_before", &q.StartBefore},
+		{"end_after", &q.EndAfter},
+		{"end_before", &q.EndBefore},
+	}
+	for _, d := range dates {
+		raw := values.Get(d.key)
+		if raw == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", raw); err != nil {
+			return q, badRequest("invalid %s: expected YYYY-MM-DD", d.key)
+		}
+		*d.target = raw
+	}
+
+	if raw := values.Get("available"); raw != "" {
+		available, err := strconv.ParseBool(raw)
+		if err != nil {
+			return q, badRequest("invalid available: expected true or false")
+		}
+		q.Available = &available
+	}
+
+	if near := values.Get("near"); near != "" {
+		city, ok := common.LookupCity(near)
+		if !ok {
+			return q, badRequest("unknown near city %q", near)
+		}
+		q.Near = &city
+		q.Sort = "distance"
+	}
+	if raw := values.Get("radius_km"); raw != "" {
+		radius, err := strconv.ParseFloat(raw, 64)
+		if err != nil || radius <= 0 {
+			return q, badRequest("invalid radius_km: expected a positive number")
+		}
+		if q.Near == nil {
+			return q, badRequest("radius_km requires near")
+		}
+		q.RadiusKm = radius
+	}
+
+	if sort := values.Get("sort"); sort != "" {
+		if _, ok := offerSortColumns[sort]; !ok {
+			return q, badRequest("invalid sort %q", sort)
+		}
+		if sort == "distance" && q.Near == nil {
+			return q, badRequest("sort distance requires near")
+		}
+		q.Sort = sort
+	}
+
+	switch order := values.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		return q, badRequest("invalid order %q: expected asc or desc", order)
+	}
+
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return q, badRequest("invalid limit: expected a positive integer")
+		}
+		q.Limit = min(limit, maxOffersPageSize)
+	}
+
+	if raw := values.Get("cursor"); raw != "" {
+		cursor, err := decodeOfferCursor(raw)
+		if err != nil {
+			return q, err
+		}
+		if cursor.Sort != q.Sort || cursor.Desc != q.Desc {
+			return q, badRequest("cursor does not match the requested sort order")
+		}
+		q.Cursor = cursor
+	}
+
+	return q, nil
+}
+
+// parseOptionalInt parses an optional integer query parameter.
+func parseOptionalInt(values url.Values, key string) (*int, error) {
+	raw := values.Get(key)
+	if raw == "" {
+		return nil, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, badRequest("invalid %s: expected an integer", key)
+	}
+	return &value, nil
+}
+
+// buildSQL renders the SELECT statement for the query; it fetches one extra row to detect a next page.
+// The selected columns are offerColumns followed by the distance to the near city, NULL without one.
+func (q offerQuery) buildSQL() (string, []interface{}) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+
+	addCondition := func(format string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if q.City != "" {
+		addCondition("city = $%d", q.City)
+	}
+	if q.Domain != "" {
+		addCondition("domain = $%d", q.Domain)
+	}
+	if q.EmployerID != nil {
+		addCondition("employer_id = $%d", *q.EmployerID)
+	}
+	if q.MinSalary != nil {
+		addCondition("salary_eur_monthly >= $%d", *q.MinSalary)
+	}
+	if q.MaxSalary != nil {
+		addCondition("salary_eur_monthly <= $%d", *q.MaxSalary)
+	}
+	if q.StartAfter != "" {
+		addCondition("start_date >= $%d::date", q.StartAfter)
+	}
+	if q.StartBefore != "" {
+		addCondition("start_date <= $%d::date", q.StartBefore)
+	}
+	if q.EndAfter != "" {
+		addCondition("end_date >= $%d::date", q.EndAfter)
+	}
+	if q.EndBefore != "" {
+		addCondition("end_date <= $%d::date", q.EndBefore)
+	}
+	if q.Available != nil {
+		addCondition("available = $%d", *q.Available)
+	}
+	if q.Search != "" {
+		addCondition(`title ILIKE $%d ESCAPE '\'`, "%"+escapeLike(q.Search)+"%")
+	}
+
+	distance := "NULL::double precision"
+	if q.Near != nil {
+		args = append(args, q.Near.Latitude, q.Near.Longitude)
+		distance = distanceSQL(len(args)-1, len(args))
+		conditions = append(conditions, "latitude IS NOT NULL")
+		if q.RadiusKm > 0 {
+			args = append(args, q.RadiusKm)
+			conditions = append(conditions, fmt.Sprintf("%s <= $%d", distance, len(args)))
+		}
+	}
+
+	sortColumn := offerSortColumns[q.Sort]
+	if q.Sort == "distance" {
+		sortColumn.column = distance
+	}
+	direction, comparison := "ASC", ">"
+	if q.Desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if q.Cursor != nil {
+		args = append(args, q.Cursor.Value, q.Cursor.ID)
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s ($%d::%s, $%d)",
+			sortColumn.column, comparison, len(args)-1, sortColumn.cast, len(args)))
+	}
+
+	query := "SELECT " + offerColumns + ", " + distance + " FROM offers"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %d", sortColumn.column, direction, direction, q.Limit+1)
+
+	return query, args
+}
+
+// escapeLike escapes LIKE wildcards so free-text search matches them literally.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// listOffers runs an offer query and returns one page of results.
+func listOffers(q offerQuery) (common.OfferPage, error) {
+	query, args := q.buildSQL()
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return common.OfferPage{}, fmt.Errorf("failed to query offers: %w", err)
+	}
+	defer rows.Close()
+
+	page := common.OfferPage{Offers: []common.Offer{}}
+	for rows.Next() {
+		var offer common.Offer
+		if err := rows.Scan(append(offerFields(&offer), &offer.DistanceKm)...); err != nil {
+			return common.OfferPage{}, fmt.Errorf("failed to scan offer: %w", err)
+		}
+		page.Offers = append(page.Offers, offer)
+	}
+	if err := rows.Err(); err != nil {
+		return common.OfferPage{}, fmt.Errorf("failed iterating offers: %w", err)
+	}
+
+	if len(page.Offers) > q.Limit {
+		page.Offers = page.Offers[:q.Limit]
+		page.NextCursor = q.cursorAfter(page.Offers[len(page.Offers)-1])
+	}
+
+	return page, nil
+}
+
+// cursorAfter encodes the position following offer in the query's sort order.
+func (q offerQuery) cursorAfter(offer common.Offer) string {
+	cursor := offerCursor{Sort: q.Sort, Desc: q.Desc, ID: offer.ID}
+	switch q.Sort {
+	case "title":
+		cursor.Value = offer.Title
+	case "salary":
+		cursor.Value = strconv.Itoa(offer.SalaryEURMonthly)
+	case "start_date":
+		cursor.Value = offer.StartDate
+	case "end_date":
+		cursor.Value = offer.EndDate
+	case "distance":
+		if offer.DistanceKm != nil {
+			cursor.Value = strconv.FormatFloat(*offer.DistanceKm, 'f', -1, 64)
+		}
+	default:
+		cursor.Value = strconv.Itoa(offer.ID)
+	}
+
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeOfferCursor parses an opaque cursor produced by cursorAfter.
+func decodeOfferCursor(raw string) (*offerCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, badRequest("invalid cursor")
+	}
+
+	var cursor offerCursor
+	if err := json.Unmarshal(decoded, &cursor); err != nil {
+		return nil, badRequest("invalid cursor")
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/thomasrubini/polymove/common"
+)
+
+const (
+	maxOfferRequirements     = 20
+	maxRequirementNameLength = 64
+
+	// mandatoryRequirementMark suffixes mandatory requirements in CSV imports, as in "go*; docker".
+	mandatoryRequirementMark = "*"
+)
+
+// requirementsColumn scans a JSONB requirement list selected with offerColumns.
+type requirementsColumn struct {
+	dest *[]common.Requirement
+}
+
+func (c requirementsColumn) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*c.dest = []common.Requirement{}
+		return nil
+	default:
+		return fmt.Errorf("unsupported requirements type %T", src)
+	}
+
+	requirements := []common.Requirement{}
+	if err := json.Unmarshal(data, &requirements); err != nil {
+		return fmt.Errorf("invalid requirements: %w", err)
+	}
+	*c.dest = requirements
+	return nil
+}
+
+// requirementsValue encodes a requirement list for a JSONB column.
+func requirementsValue(requirements []common.Requirement) string {
+	if requirements == nil {
+		return "[]"
+	}
+	data, _ := json.Marshal(requirements)
+	return string(data)
+}
+
+// validateRequirements returns the problems of one requirement list of an offer.
+func validateRequirements(field string, requirements []common.Requirement) []FieldError {
+	var problems []FieldError
+	if len(requirements) > maxOfferRequirements {
+		problems = append(problems, FieldError{Field: field, Message: fmt.Sprintf("must list at most %d entries", maxOfferRequirements)})
+	}
+	for _, requirement := range requirements {
+		if len(requirement.Name) > maxRequirementNameLength {
+			problems = append(problems, FieldError{Field: field, Message: fmt.Sprintf("%q must be at most %d characters", requirement.Name, maxRequirementNameLength)})
+		}
+	}
+	return problems
+}
+
+// parseRequirementList reads a CSV requirement cell: names separated by semicolons, mandatory ones marked with "*".
+func parseRequirementList(cell string) []common.Requirement {
+	var requirements []common.Requirement
+	for _, name := range strings.Split(cell, ";") {
+		name = strings.TrimSpace(name)
+		mandatory := strings.HasSuffix(name, mandatoryRequirementMark)
+		requirements = append(requirements, common.Requirement{
+			Name:      strings.TrimSuffix(name, mandatoryRequirementMark),
+			Mandatory: mandatory,
+		})
+	}
+	return common.NormalizeRequirements(requirements)
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/thomasrubini/polymove/common"
+)
+
+const (
+	reservationStatusPending   = "pending"
+	reservationStatusConfirmed = "confirmed"
+	reservationStatusReleased  = "released"
+	reservationStatusExpired   = "expired"
+
+	defaultReservationTTL    = 15 * time.Minute
+	maxReservationTTL        = 24 * time.Hour
+	reservationSweepInterval = time.Minute
+)
+
+// reservationColumns lists the offer_reservations columns in the order expected by scanReservation.
+const reservationColumns = `id, offer_id, holder, status,
+	COALESCE(TO_CHAR(expires_at, 'YYYY-MM-DD"T"HH24:MI:SS"Z"'), ''), TO_CHAR(created_at, 'YYYY-MM-DD"T"HH24:MI:SS"Z"')`
+
+// ReservationRequest is the payload of POST /offers/{id}/reservations.
+// TTLSeconds bounds how long the seat is held before it must be confirmed.
+type ReservationRequest struct {
+	Holder     string `json:"holder"`
+	TTLSeconds int    `json:"ttl_seconds"`
+}
+
+// scanReservation reads one reservation selected with reservationColumns.
+func scanReservation(row rowScanner, reservation *common.Reservation) error {
+	return row.Scan(&reservation.ID, &reservation.OfferID, &reservation.Holder, &reservation.Status, &reservation.ExpiresAt, &reservation.CreatedAt)
+}
+
+// reservationIDFromRequest parses the {id} route variable of reservation routes.
+func reservationIDFromRequest(r *http.Request) (int, error) {
+	raw := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(raw)
+	if err != nil || id <= 0 {
+		return 0, badRequest("invalid reservation id %q", raw)
+	}
+	return id, nil
+}
+
+// lockOffer loads an offer with FOR UPDATE, along with whether it was closed for being full.
+// Every seat change goes through this lock, which keeps seats_reserved consistent.
+func lockOffer(tx *sql.Tx, id int) (common.Offer, bool, error) {
+	var (
+		offer  common.Offer
+		filled bool
+	)
+	err := tx.QueryRow("SELECT "+offerColumns+", filled FROM offers WHERE id = $1 FOR UPDATE", id).Scan(append(offerFields(&offer), &filled)...)
+	if err == sql.ErrNoRows {
+		return offer, false, notFound("offer", id)
+	}
+	if err != nil {
+		return offer, false, fmt.Errorf("failed to get offer: %w", err)
+	}
+	return offer, filled, nil
+}
+
+// takeSeat reserves one seat of a locked offer and closes the offer when it was the last one.
+func takeSeat(tx *sql.Tx, offer common.Offer, actor string) (common.Offer, error) {
+	before := offer
+	query := `
+	UPDATE offers SET seats_reserved = seats_reserved + 1,
+		available = seats_reserved + 1 < seats,
+		filled = seats_reserved + 1 >= seats
+	WHERE id = $1
+	RETURNING ` + offerColumns
+	if err := scanOffer(tx.QueryRow(query, offer.ID), &offer); err != nil {
+		return offer, fmt.Errorf("failed to reserve seat: %w", err)
+	}
+
+	if before.Available != offer.Available {
+		if err := recordAvailabilityChange(tx, before, offer, actor); err != nil {
+			return offer, err
+		}
+	}
+	return offer, nil
+}
+
+// freeSeats gives back n seats of a locked offer and reopens it if it was only closed for being full.
+func freeSeats(tx *sql.Tx, offer common.Offer, n int, actor string) (common.Offer, error) {
+	before := offer
+	query := `
+	UPDATE offers SET seats_reserved = seats_reserved - $2,
+		available = available OR (filled AND start_date >= CURRENT_DATE AND end_date > CURRENT_DATE),
+		filled = filled AND NOT (start_date >= CURRENT_DATE AND end_date > CURRENT_DATE)
+	WHERE id = $1
+	RETURNING ` + offerColumns
+	if err := scanOffer(tx.QueryRow(query, offer.ID, n), &offer); err != nil {
+		return offer, fmt.Errorf("failed to free seats: %w", err)
+	}
+
+	if before.Available != offer.Available {
+		if err := recordAvailabilityChange(tx, before, offer, actor); err != nil {
+			return offer, err
+		}
+	}
+	return offer, nil
+}
+
+// recordAvailabilityChange records the revision and offer.updated event of an offer filled up or reopened by seat changes.
+func recordAvailabilityChange(tx *sql.Tx, before, after common.Offer, actor string) error {
+	changes := diffOffers(before, after)
+	if err := recordOfferRevision(tx, after.ID, revisionActionUpdated, actor, changes); err != nil {
+		return err
+	}
+	return enqueueOfferUpdatedEvent(tx, after, changes)
+}
+
+// lapseReservations expires the pending reservations of a locked offer that were not confirmed in time.
+func lapseReservations(tx *sql.Tx, offer common.Offer) (common.Offer, error) {
+	result, err := tx.Exec(
+		"UPDATE offer_reservations SET status = $1 WHERE offer_id = $2 AND status = $3 AND expires_at <= CURRENT_TIMESTAMP",
+		reservationStatusExpired, offer.ID, reservationStatusPending,
+	)
+	if err != nil {
+		return offer, fmt.Errorf("failed to expire reservations: %w", err)
+	}
+
+	lapsed, err := result.RowsAffected()
+	if err != nil {
+		return offer, fmt.Errorf("failed to count expired reservations: %w", err)
+	}
+	if lapsed == 0 {
+		return offer, nil
+	}
+
+	return freeSeats(tx, offer, int(lapsed), systemActor)
+}
+
+// reserveSeat handles POST /offers/{id}/reservations - Holds one seat of an offer until it is confirmed or released
+func reserveSeat(w http.ResponseWriter, r *http.Request) error {
+	id, err := offerIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	var req ReservationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return badRequest("failed to decode request body: %v", err)
+	}
+
+	var problems []FieldError
+	req.Holder = strings.TrimSpace(req.Holder)
+	if req.Holder == "" {
+		problems = append(problems, FieldError{Field: "holder", Message: "is required"})
+	} else if len(req.Holder) > maxOfferTextLength {
+		problems = append(problems, FieldError{Field: "holder", Message: "must be at most 255 characters"})
+	}
+	ttl := defaultReservationTTL
+	if req.TTLSeconds != 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+		if ttl < 0 || ttl > maxReservationTTL {
+			problems = append(problems, FieldError{Field: "ttl_seconds", Message: fmt.Sprintf("must be between 1 and %d", int(maxReservationTTL.Seconds()))})
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Fields: problems}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	offer, _, err := lockOffer(tx, id)
+	if err != nil {
+		return err
+	}
+	if offer, err = lapseReservations(tx, offer); err != nil {
+		return err
+	}
+
+	if !offer.Available {
+		if offer.SeatsLeft <= 0 {
+			return conflict("offer %d has no seats left", id)
+		}
+		return conflict("offer %d is not available", id)
+	}
+
+	var reservation common.Reservation
+	err = scanReservation(tx.QueryRow(
+		`INSERT INTO offer_reservations (offer_id, holder, status, expires_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP + make_interval(secs => $4))
+		RETURNING `+reservationColumns,
+		id, req.Holder, reservationStatusPending, int(ttl.Seconds()),
+	), &reservation)
+	if err != nil {
+		return fmt.Errorf("failed to insert reservation: %w", err)
+	}
+
+	if offer, err = takeSeat(tx, offer, actorFromRequest(r)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit reservation: %w", err)
+	}
+	outbox.Notify()
+
+	log.Printf("Reserved seat offer_id=%d reservation_id=%d holder=%q seats_left=%d", id, reservation.ID, reservation.Holder, offer.SeatsLeft)
+
+	return NewResponseWriter(w).JSON(http.StatusCreated, reservation)
+}
+
+// getReservation handles GET /reservations/{id} - Retrieves a reservation
+func getReservation(w http.ResponseWriter, r *http.Request) error {
+	id, err := reservationIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	var reservation common.Reservation
+	err = scanReservation(db.QueryRow("SELECT "+reservationColumns+" FROM offer_reservations WHERE id = $1", id), &reservation)
+	if err == sql.ErrNoRows {
+		return notFound("reservation", id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get reservation: %w", err)
+	}
+
+	return NewResponseWriter(w).JSON(http.StatusOK, reservation)
+}
+
+// lockReservation locks a reservation and its offer, offer first like every other seat change.
+// It lapses the offer's overdue pending reservations on the way, so the returned status is current.
+func lockReservation(tx *sql.Tx, id int) (common.Reservation, common.Offer, error) {
+	var (
+		reservation common.Reservation
+		offer       common.Offer
+	)
+
+	var offerID int
+	err := tx.QueryRow("SELECT offer_id FROM offer_reservations WHERE id = $1", id).Scan(&offerID)
+	if err == sql.ErrNoRows {
+		return reservation, offer, notFound("reservation", id)
+	}
+	if err != nil {
+		return reservation, offer, fmt.Errorf("failed to get reservation: %w", err)
+	}
+
+	offer, _, err = lockOffer(tx, offerID)
+	if err != nil {
+		return reservation, offer, err
+	}
+	if offer, err = lapseReservations(tx, offer); err != nil {
+		return reservation, offer, err
+	}
+
+	err = scanReservation(tx.QueryRow("SELECT "+reservationColumns+" FROM offer_reservations WHERE id = $1 FOR UPDATE", id), &reservation)
+	if err == sql.ErrNoRows {
+		return reservation, offer, notFound("reservation", id)
+	}
+	if err != nil {
+		return reservation, offer, fmt.Errorf("failed to get reservation: %w", err)
+	}
+
+	return reservation, offer, nil
+}
+
+// confirmReservation handles POST /reservations/{id}/confirm - Turns a pending hold into a taken seat
+func confirmReservation(w http.ResponseWriter, r *http.Request) error {
+	id, err := reservationIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	reservation, _, err := lockReservation(tx, id)
+	if err != nil {
+		return err
+	}
+
+	switch reservation.Status {
+	case reservationStatusConfirmed:
+		return NewResponseWriter(w).JSON(http.StatusOK, reservation)
+	case reservationStatusPending:
+	default:
+		// Commit the lapse found by lockReservation before reporting it.
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit reservation expiry: %w", err)
+		}
+		outbox.Notify()
+		return conflict("reservation %d is %s", id, reservation.Status)
+	}
+
+	err = scanReservation(tx.QueryRow(
+		"UPDATE offer_reservations SET status = $1, expires_at = NULL WHERE id = $2 RETURNING "+reservationColumns,
+		reservationStatusConfirmed, id,
+	), &reservation)
+	if err != nil {
+		return fmt.Errorf("failed to confirm reservation: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit reservation: %w", err)
+	}
+	outbox.Notify()
+
+	log.Printf("Confirmed reservation id=%d offer_id=%d holder=%q", reservation.ID, reservation.OfferID, reservation.Holder)
+
+	return NewResponseWriter(w).JSON(http.StatusOK, reservation)
+}
+
+// releaseReservation handles POST /reservations/{id}/release - Gives a held or confirmed seat back
+func releaseReservation(w http.ResponseWriter, r *http.Request) error {
+	id, err := reservationIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	reservation, offer, err := lockReservation(tx, id)
+	if err != nil {
+		return err
+	}
+
+	if reservation.Status == reservationStatusPending || reservation.Status == reservationStatusConfirmed {
+		err = scanReservation(tx.QueryRow(
+			"UPDATE offer_reservations SET status = $1, expires_at = NULL WHERE id = $2 RETURNING "+reservationColumns,
+			reservationStatusReleased, id,
+		), &reservation)
+		if err != nil {
+			return fmt.Errorf("failed to release reservation: %w", err)
+		}
+
+		if _, err := freeSeats(tx, offer, 1, actorFromRequest(r)); err != nil {
+			return err
+		}
+
+		log.Printf("Released reservation id=%d offer_id=%d holder=%q", reservation.ID, reservation.OfferID, reservation.Holder)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit reservation release: %w", err)
+	}
+	outbox.Notify()
+
+	return NewResponseWriter(w).JSON(http.StatusOK, reservation)
+}
+
+// runReservationSweeper frees the seats of unconfirmed reservations once they lapse, so full offers reopen
+// without waiting for the next reservation attempt.
+func runReservationSweeper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := sweepReservations(); err != nil {
+			log.Printf("Reservation sweep failed: %v", err)
+		}
+	}
+}
+
+// sweepReservations lapses overdue reservations, one offer per transaction.
+func sweepReservations() error {
+	rows, err := db.Query(
+		"SELECT DISTINCT offer_id FROM offer_reservations WHERE status = $1 AND expires_at <= CURRENT_TIMESTAMP",
+		reservationStatusPending,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query lapsed reservations: %w", err)
+	}
+
+	var offerIDs []int
+	for rows.Next() {
+		var offerID int
+		if err := rows.Scan(&offerID); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to scan offer id: %w", err)
+		}
+		offerIDs = append(offerIDs, offerID)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed iterating lapsed reservations: %w", err)
+	}
+
+	for _, offerID := range offerIDs {
+		if err := sweepOfferReservations(offerID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sweepOfferReservations lapses the overdue reservations of one offer.
+func sweepOfferReservations(offerID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	offer, _, err := lockOffer(tx, offerID)
+	if err != nil {
+		return err
+	}
+	if _, err := lapseReservations(tx, offer); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit lapsed reservations: %w", err)
+	}
+	outbox.Notify()
+	return nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/thomasrubini/polymove/common"
+	"github.com/thomasrubini/polymove/common/auth"
+)
+
+const (
+	revisionActionCreated = "created"
+	revisionActionUpdated = "updated"
+	revisionActionClosed  = "closed"
+	revisionActionDeleted = "deleted"
+	revisionActionExpired = "expired"
+
+	// systemActor identifies changes made by Erasmumu itself, such as the expiry scheduler.
+	systemActor = "system"
+)
+
+// OfferRevision is one entry of an offer's change history.
+type OfferRevision struct {
+	OfferID   int                  `json:"offer_id"`
+	Revision  int                  `json:"revision"`
+	Action    string               `json:"action"`
+	ChangedBy string               `json:"changed_by"`
+	ChangedAt string               `json:"changed_at"`
+	Changes   []common.FieldChange `json:"changes"`
+}
+
+// actorFromRequest names who is changing an offer from the verified token only; client-supplied headers are never
+// trusted, so the history cannot be forged.
+func actorFromRequest(r *http.Request) string {
+	if claims, ok := auth.FromContext(r.Context()); ok {
+		return claims.Subject()
+	}
+	return "anonymous"
+}
+
+// diffOffers lists the fields that differ between two versions of an offer.
+func diffOffers(before, after common.Offer) []common.FieldChange {
+	var changes []common.FieldChange
+	add := func(field string, from, to interface{}) {
+		if from != to {
+			changes = append(changes, common.FieldChange{Field: field, From: from, To: to})
+		}
+	}
+	addList := func(field string, from, to []common.Requirement) {
+		if !reflect.DeepEqual(from, to) {
+			changes = append(changes, common.FieldChange{Field: field, From: from, To: to})
+		}
+	}
+
+	add("title", before.Title, after.Title)
+	add("link", before.Link, after.Link)
+	add("city", before.City, after.City)
+	add("domain", before.Domain, after.Domain)
+	add("salary", before.Salary, after.Salary)
+	add("currency", before.Currency, after.Currency)
+	add("payPeriod", before.PayPeriod, after.PayPeriod)
+	add("startDate", before.StartDate, after.StartDate)
+	add("endDate", before.EndDate, after.EndDate)
+	add("available", before.Available, after.Available)
+	add("seats", before.Seats, after.Seats)
+	add("employerId", employerIDValue(before), employerIDValue(after))
+	addList("skills", before.Skills, after.Skills)
+	addList("languages", before.Languages, after.Languages)
+
+	return changes
+}
+
+// employerIDValue unwraps the employer ID so diffs compare values, not pointers.
+func employerIDValue(offer common.Offer) interface{} {
+	if offer.EmployerID == nil {
+		return nil
+	}
+	return *offer.EmployerID
+}
+
+// creationChanges describes a new offer as a diff from nothing.
+func creationChanges(offer common.Offer) []common.FieldChange {
+	return []common.FieldChange{
+		{Field: "title", To: offer.Title},
+		{Field: "link", To: offer.Link},
+		{Field: "city", To: offer.City},
+		{Field: "domain", To: offer.Domain},
+		{Field: "salary", To: offer.Salary},
+		{Field: "currency", To: offer.Currency},
+		{Field: "payPeriod", To: offer.PayPeriod},
+		{Field: "startDate", To: offer.StartDate},
+		{Field: "endDate", To: offer.EndDate},
+		{Field: "available", To: offer.Available},
+		{Field: "seats", To: offer.Seats},
+		{Field: "employerId", To: employerIDValue(offer)},
+		{Field: "skills", To: offer.Skills},
+		{Field: "languages", To: offer.Languages},
+	}
+}
+
+// recordOfferRevision appends a revision to the offer history inside tx.
+// Callers hold a lock on the offer row, which keeps revision numbers sequential.
+func recordOfferRevision(tx *sql.Tx, offerID int, action, actor string, changes []common.FieldChange) error {
+	if changes == nil {
+		changes = []common.FieldChange{}
+	}
+
+	payload, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal offer changes: %w", err)
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO offer_revisions (offer_id, revision, action, changed_by, changes)
+		SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4 FROM offer_revisions WHERE offer_id = $1`,
+		offerID, action, actor, payload,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record offer revision: %w", err)
+	}
+
+	return nil
+}
+
+// getOfferHistory handles GET /offers/{id}/history - Lists every revision of an offer, oldest first.
+// History outlives the offer, so deleted offers still return their revisions.
+func getOfferHistory(w http.ResponseWriter, r *http.Request) error {
+	id, err := offerIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.Query(
+		`SELECT offer_id, revision, action, changed_by, TO_CHAR(changed_at, 'YYYY-MM-DD"T"HH24:MI:SS"Z"'), changes
+		FROM offer_revisions WHERE offer_id = $1 ORDER BY revision`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query offer history: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []OfferRevision{}
+	for rows.Next() {
+		var (
+			revision OfferRevision
+			changes  []byte
+		)
+		if err := rows.Scan(&revision.OfferID, &revision.Revision, &revision.Action, &revision.ChangedBy, &revision.ChangedAt, &changes); err != nil {
+			return fmt.Errorf("failed to scan offer revision: %w", err)
+		}
+		if err := json.Unmarshal(changes, &revision.Changes); err != nil {
+			return fmt.Errorf("failed to decode offer changes: %w", err)
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed iterating offer history: %w", err)
+	}
+
+	if len(revisions) == 0 {
+		var exists bool
+		if err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM offers WHERE id = $1)", id).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to get offer: %w", err)
+		}
+		if !exists {
+			return notFound("offer", id)
+		}
+	}
+
+	return NewResponseWriter(w).JSON(http.StatusOK, revisions)
+}
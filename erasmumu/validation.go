@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/thomasrubini/polymove/common"
+)
+
+const (
+	maxOfferTextLength = 255
+
+	// defaultOfferSeats is the capacity of offers created without a seats field.
+	defaultOfferSeats = 1
+)
+
+// defaultOfferDomains is used when OFFER_DOMAINS is not set.
+const defaultOfferDomains = "software,cybersecurity,data,networks"
+
+// knownOfferDomains returns the domains offers may be published in, from OFFER_DOMAINS.
+func knownOfferDomains() map[string]bool {
+	domains := make(map[string]bool)
+	for _, domain := range strings.Split(getEnv("OFFER_DOMAINS", defaultOfferDomains), ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			domains[domain] = true
+		}
+	}
+	return domains
+}
+
+// applyOfferDefaults fills the optional fields of a new offer.
+func applyOfferDefaults(offer *common.Offer) {
+	if offer.Seats == 0 {
+		offer.Seats = defaultOfferSeats
+	}
+	offer.Currency = strings.ToUpper(strings.TrimSpace(offer.Currency))
+	if offer.Currency == "" {
+		offer.Currency = defaultCurrency
+	}
+	if offer.PayPeriod == "" {
+		offer.PayPeriod = payPeriodMonthly
+	}
+	offer.Skills = common.NormalizeRequirements(offer.Skills)
+	offer.Languages = common.NormalizeRequirements(offer.Languages)
+}
+
+// validateOffer returns one FieldError per problem found in an offer, or nil if it can be stored.
+func validateOffer(offer common.Offer) []FieldError {
+	var problems []FieldError
+	add := func(field, message string) {
+		problems = append(problems, FieldError{Field: field, Message: message})
+	}
+
+	if strings.TrimSpace(offer.Title) == "" {
+		add("title", "is required")
+	} else if len(offer.Title) > maxOfferTextLength {
+		add("title", "must be at most 255 characters")
+	}
+
+	if strings.TrimSpace(offer.Link) == "" {
+		add("link", "is required")
+	} else if link, err := url.Parse(offer.Link); err != nil || (link.Scheme != "http" && link.Scheme != "https") || link.Host == "" {
+		add("link", "must be an absolute http or https URL")
+	}
+
+	if strings.TrimSpace(offer.City) == "" {
+		add("city", "is required")
+	} else if len(offer.City) > maxOfferTextLength {
+		add("city", "must be at most 255 characters")
+	}
+
+	if strings.TrimSpace(offer.Domain) == "" {
+		add("domain", "is required")
+	} else if !knownOfferDomains()[offer.Domain] {
+		add("domain", "is not a known domain")
+	}
+
+	if offer.Salary < 0 {
+		add("salary", "must not be negative")
+	}
+
+	if !currencyCode.MatchString(offer.Currency) {
+		add("currency", "must be an ISO 4217 code")
+	}
+
+	if !knownPayPeriod(offer.PayPeriod) {
+		add("payPeriod", "must be monthly, hourly or total")
+	}
+
+	if offer.Seats < 1 {
+		add("seats", "must be at least 1")
+	}
+
+	problems = append(problems, validateRequirements("skills", offer.Skills)...)
+	problems = append(problems, validateRequirements("languages", offer.Languages)...)
+
+	startDate, startErr := time.Parse("2006-01-02", offer.StartDate)
+	if startErr != nil {
+		add("startDate", "must be a YYYY-MM-DD date")
+	}
+	endDate, endErr := time.Parse("2006-01-02", offer.EndDate)
+	if endErr != nil {
+		add("endDate", "must be a YYYY-MM-DD date")
+	}
+	if startErr == nil && endErr == nil && endDate.Before(startDate) {
+		add("endDate", "must not be before startDate")
+	}
+
+	return problems
+}
+
+// checkOffer wraps validateOffer problems into a ValidationError.
+func checkOffer(offer common.Offer) error {
+	if problems := validateOffer(offer); len(problems) > 0 {
+		return &ValidationError{Fields: problems}
+	}
+	return nil
+}
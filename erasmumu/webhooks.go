@@ -0,0 +1,689 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+
+	"github.com/thomasrubini/polymove/common"
+)
+
+const (
+	webhookStatusPending   = "pending"
+	webhookStatusDelivered = "delivered"
+	webhookStatusFailed    = "failed"
+
+	webhookDispatchInterval = 5 * time.Second
+	webhookBatchSize        = 20
+	webhookTimeout          = 10 * time.Second
+
+	// A delivery is retried after webhookBaseBackoff, doubling up to webhookMaxBackoff, and gives up
+	// after webhookMaxAttempts.
+	webhookBaseBackoff = 30 * time.Second
+	webhookMaxBackoff  = time.Hour
+	webhookMaxAttempts = 8
+
+	// webhookDisableAfter consecutive failed attempts, over any deliveries, disable a subscription.
+	webhookDisableAfter = 10
+
+	minWebhookSecretLength = 16
+
+	webhookEventHeader     = "X-Erasmumu-Event"
+	webhookDeliveryHeader  = "X-Erasmumu-Delivery"
+	webhookSignatureHeader = "X-Erasmumu-Signature"
+)
+
+// webhookEventTypes are the routing keys partners can subscribe to.
+var webhookEventTypes = map[string]bool{
+	common.RoutingKeyOfferCreated: true,
+	common.RoutingKeyOfferUpdated: true,
+	common.RoutingKeyOfferClosed:  true,
+	common.RoutingKeyOfferDeleted: true,
+	common.RoutingKeyOfferExpired: true,
+}
+
+// webhookClient sends webhook requests; it is a variable so a local httptest receiver can stand in for partners.
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// WebhookSubscription is a partner endpoint notified of offer events. The secret is only returned on creation.
+type WebhookSubscription struct {
+	ID                  int      `json:"id"`
+	URL                 string   `json:"url"`
+	EventTypes          []string `json:"eventTypes"`
+	Secret              string   `json:"secret,omitempty"`
+	Enabled             bool     `json:"enabled"`
+	ConsecutiveFailures int      `json:"consecutiveFailures"`
+	DisabledAt          string   `json:"disabledAt,omitempty"`
+	CreatedAt           string   `json:"createdAt"`
+}
+
+// WebhookRequest is the payload for registering or updating a subscription; an empty secret is generated on
+// creation and kept on update, a nil Enabled keeps subscriptions enabled.
+type WebhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"eventTypes"`
+	Secret     string   `json:"secret"`
+	Enabled    *bool    `json:"enabled"`
+}
+
+// WebhookDelivery is one event sent, or still to send, to a subscription.
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	SubscriptionID int             `json:"subscriptionId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	LastStatusCode *int            `json:"lastStatusCode,omitempty"`
+	LastError      string          `json:"lastError,omitempty"`
+	NextAttemptAt  string          `json:"nextAttemptAt,omitempty"`
+	CreatedAt      string          `json:"createdAt"`
+	DeliveredAt    string          `json:"deliveredAt,omitempty"`
+}
+
+// webhookEnvelope is the body POSTed to partners.
+type webhookEnvelope struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt string          `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// webhookColumns lists the webhook_subscriptions columns in the order expected by scanWebhook.
+const webhookColumns = `id, url, event_types, enabled, consecutive_failures,
+	COALESCE(TO_CHAR(disabled_at, 'YYYY-MM-DD"T"HH24:MI:SS"Z"'), ''), TO_CHAR(created_at, 'YYYY-MM-DD"T"HH24:MI:SS"Z"')`
+
+// scanWebhook reads one subscription selected with webhookColumns.
+func scanWebhook(row rowScanner, webhook *WebhookSubscription) error {
+	return row.Scan(&webhook.ID, &webhook.URL, pq.Array(&webhook.EventTypes), &webhook.Enabled,
+		&webhook.ConsecutiveFailures, &webhook.DisabledAt, &webhook.CreatedAt)
+}
+
+// webhookDeliveryColumns lists the webhook_deliveries columns in the order expected by scanWebhookDelivery.
+const webhookDeliveryColumns = `id, subscription_id, event_type, payload, status, attempts, last_status_code, last_error,
+	CASE WHEN status = 'pending' THEN TO_CHAR(next_attempt_at, 'YYYY-MM-DD"T"HH24:MI:SS"Z"') ELSE '' END,
+	TO_CHAR(created_at, 'YYYY-MM-DD"T"HH24:MI:SS"Z"'), COALESCE(TO_CHAR(delivered_at, 'YYYY-MM-DD"T"HH24:MI:SS"Z"'), '')`
+
+// scanWebhookDelivery reads one delivery selected with webhookDeliveryColumns.
+func scanWebhookDelivery(row rowScanner, delivery *WebhookDelivery) error {
+	return row.Scan(&delivery.ID, &delivery.SubscriptionID, &delivery.EventType, &delivery.Payload, &delivery.Status,
+		&delivery.Attempts, &delivery.LastStatusCode, &delivery.LastError, &delivery.NextAttemptAt,
+		&delivery.CreatedAt, &delivery.DeliveredAt)
+}
+
+// webhookIDFromRequest parses the {id} route variable of webhook routes.
+func webhookIDFromRequest(r *http.Request) (int, error) {
+	raw := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(raw)
+	if err != nil || id <= 0 {
+		return 0, badRequest("invalid webhook id %q", raw)
+	}
+	return id, nil
+}
+
+// decodeWebhook reads and validates a subscription payload; creating requires a secret or generates one.
+func decodeWebhook(r *http.Request, creating bool) (WebhookRequest, error) {
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return req, badRequest("failed to decode request body: %v", err)
+	}
+	req.URL = strings.TrimSpace(req.URL)
+
+	var problems []FieldError
+	if target, err := url.Parse(req.URL); err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		problems = append(problems, FieldError{Field: "url", Message: "must be an absolute http or https URL"})
+	}
+
+	eventTypes := make([]string, 0, len(req.EventTypes))
+	seen := make(map[string]bool, len(req.EventTypes))
+	for _, eventType := range req.EventTypes {
+		eventType = strings.TrimSpace(eventType)
+		if !webhookEventTypes[eventType] {
+			problems = append(problems, FieldError{Field: "eventTypes", Message: fmt.Sprintf("%q is not a known event type", eventType)})
+			continue
+		}
+		if !seen[eventType] {
+			seen[eventType] = true
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	if len(req.EventTypes) == 0 {
+		problems = append(problems, FieldError{Field: "eventTypes", Message: "must list at least one event type"})
+	}
+	req.EventTypes = eventTypes
+
+	if req.Secret == "" && creating {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return req, err
+		}
+		req.Secret = secret
+	}
+	if req.Secret != "" && len(req.Secret) < minWebhookSecretLength {
+		problems = append(problems, FieldError{Field: "secret", Message: fmt.Sprintf("must be at least %d characters", minWebhookSecretLength)})
+	}
+
+	if len(problems) > 0 {
+		return req, &ValidationError{Fields: problems}
+	}
+	return req, nil
+}
+
+// newWebhookSecret returns a random hex secret for subscriptions registered without one.
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// signWebhookPayload returns the X-Erasmumu-Signature value of body: "sha256=" and the hex HMAC-SHA256 of
+// the body keyed with the subscription secret.
+func signWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// enqueueWebhookDeliveries records one delivery of the event per enabled subscription listening to it, inside tx.
+func enqueueWebhookDeliveries(tx *sql.Tx, eventType string, event interface{}) error {
+	if !webhookEventTypes[eventType] {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s webhook payload: %w", eventType, err)
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO webhook_deliveries (subscription_id, event_type, payload) SELECT id, $1, $2 FROM webhook_subscriptions WHERE enabled AND $1 = ANY(event_types)",
+		eventType, string(payload),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store %s webhook deliveries: %w", eventType, err)
+	}
+	return nil
+}
+
+// webhookBackoff is the wait before retrying a delivery that failed attempts times.
+func webhookBackoff(attempts int) time.Duration {
+	backoff := webhookBaseBackoff
+	for i := 1; i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, webhookMaxBackoff)
+}
+
+// runWebhookDispatcher sends due webhook deliveries at startup and then on every tick.
+func runWebhookDispatcher(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	store := dbWebhookStore{db: db}
+	for {
+		for {
+			sent, err := dispatchWebhooks(context.Background(), store, webhookClient)
+			if err != nil {
+				log.Printf("Webhook dispatch failed: %v", err)
+				break
+			}
+			if sent < webhookBatchSize {
+				break
+			}
+		}
+
+		<-ticker.C
+	}
+}
+
+// pendingWebhook is a due delivery with the endpoint it goes to.
+type pendingWebhook struct {
+	WebhookDelivery
+	URL    string
+	Secret string
+}
+
+// webhookFailure is how a failed attempt is recorded: Status stays pending until webhookMaxAttempts, and the
+// next attempt is due RetryIn from now. StatusCode is 0 when no response was received.
+type webhookFailure struct {
+	Attempts   int
+	Status     string
+	StatusCode int
+	Err        string
+	RetryIn    time.Duration
+}
+
+// newWebhookFailure decides what becomes of a delivery whose attempt failed with sendErr.
+func newWebhookFailure(p pendingWebhook, statusCode int, sendErr error) webhookFailure {
+	failure := webhookFailure{
+		Attempts:   p.Attempts + 1,
+		Status:     webhookStatusPending,
+		StatusCode: statusCode,
+		Err:        sendErr.Error(),
+	}
+	failure.RetryIn = webhookBackoff(failure.Attempts)
+	if failure.Attempts >= webhookMaxAttempts {
+		failure.Status = webhookStatusFailed
+	}
+	return failure
+}
+
+// webhookStore keeps the deliveries of the dispatcher. Every method is its own short transaction, so no lock is
+// held while partners are called.
+type webhookStore interface {
+	// claimWebhooks leases up to limit due deliveries of enabled subscriptions, oldest first: they are not due
+	// again until lease has passed, so replicas do not send them too, and a crashed dispatcher's get retried.
+	claimWebhooks(ctx context.Context, limit int, lease time.Duration) ([]pendingWebhook, error)
+	// recordWebhookSuccess marks a delivery as delivered and resets the failure count of its subscription.
+	recordWebhookSuccess(ctx context.Context, p pendingWebhook, statusCode int) error
+	// recordWebhookFailure records a failed attempt and returns the consecutive failures of the subscription.
+	recordWebhookFailure(ctx context.Context, p pendingWebhook, failure webhookFailure) (int, error)
+	// disableWebhookSubscription stops deliveries to a subscription until it is enabled again.
+	disableWebhookSubscription(ctx context.Context, subscriptionID int) error
+	// releaseWebhooks makes claimed deliveries that were not attempted due again.
+	releaseWebhooks(ctx context.Context, ids []int64) error
+}
+
+// webhookClaimLease outlasts the sequential sends of a whole batch.
+const webhookClaimLease = webhookBatchSize*webhookTimeout + time.Minute
+
+// dispatchWebhooks sends up to webhookBatchSize due deliveries and returns how many it claimed. The deliveries
+// are claimed first, then sent outside any transaction, and each outcome is recorded on its own; a delivery
+// whose outcome could not be recorded is sent again once its lease expires, so delivery is at least once.
+func dispatchWebhooks(ctx context.Context, store webhookStore, client *http.Client) (int, error) {
+	pending, err := store.claimWebhooks(ctx, webhookBatchSize, webhookClaimLease)
+	if err != nil {
+		return 0, err
+	}
+
+	disabled := make(map[int]bool)
+	var skipped []int64
+	for _, p := range pending {
+		if disabled[p.SubscriptionID] {
+			skipped = append(skipped, p.ID)
+			continue
+		}
+
+		statusCode, sendErr := deliverWebhook(ctx, client, p)
+		if sendErr == nil {
+			if err := store.recordWebhookSuccess(ctx, p, statusCode); err != nil {
+				log.Printf("Webhook delivery id=%d sent but not recorded: %v", p.ID, err)
+			}
+			continue
+		}
+
+		log.Printf("Webhook delivery id=%d subscription=%d failed: %v", p.ID, p.SubscriptionID, sendErr)
+		failures, err := store.recordWebhookFailure(ctx, p, newWebhookFailure(p, statusCode, sendErr))
+		if err != nil {
+			log.Printf("Webhook delivery id=%d failure not recorded: %v", p.ID, err)
+			continue
+		}
+		if failures >= webhookDisableAfter {
+			if err := store.disableWebhookSubscription(ctx, p.SubscriptionID); err != nil {
+				log.Printf("Failed to disable webhook subscription id=%d: %v", p.SubscriptionID, err)
+				continue
+			}
+			disabled[p.SubscriptionID] = true
+			log.Printf("Disabled webhook subscription id=%d after %d consecutive failures", p.SubscriptionID, failures)
+		}
+	}
+
+	if len(skipped) > 0 {
+		if err := store.releaseWebhooks(ctx, skipped); err != nil {
+			return len(pending), err
+		}
+	}
+	return len(pending), nil
+}
+
+// deliverWebhook POSTs one signed delivery and returns the response status; any non-2xx answer is an error.
+// statusCode is 0 when no response was received.
+func deliverWebhook(ctx context.Context, client *http.Client, p pendingWebhook) (int, error) {
+	body, err := json.Marshal(webhookEnvelope{ID: p.ID, Type: p.EventType, CreatedAt: p.CreatedAt, Data: p.Payload})
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode webhook: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Erasmumu-Webhooks/1.0")
+	req.Header.Set(webhookEventHeader, p.EventType)
+	req.Header.Set(webhookDeliveryHeader, strconv.FormatInt(p.ID, 10))
+	req.Header.Set(webhookSignatureHeader, signWebhookPayload(p.Secret, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// dbWebhookStore is the webhookStore backed by the webhook_deliveries and webhook_subscriptions tables.
+type dbWebhookStore struct {
+	db *sql.DB
+}
+
+func (s dbWebhookStore) claimWebhooks(ctx context.Context, limit int, lease time.Duration) ([]pendingWebhook, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		WITH due AS (
+			SELECT d.id
+			FROM webhook_deliveries d JOIN webhook_subscriptions s ON s.id = d.subscription_id
+			WHERE d.status = $1 AND s.enabled AND d.next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY d.id
+			LIMIT $2
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $3)
+		FROM due, webhook_subscriptions s
+		WHERE d.id = due.id AND s.id = d.subscription_id
+		RETURNING d.id, d.subscription_id, d.event_type, d.payload, d.attempts,
+			TO_CHAR(d.created_at, 'YYYY-MM-DD"T"HH24:MI:SS"Z"'), s.url, s.secret`,
+		webhookStatusPending, limit, lease.Seconds(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim pending webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var pending []pendingWebhook
+	for rows.Next() {
+		var p pendingWebhook
+		if err := rows.Scan(&p.ID, &p.SubscriptionID, &p.EventType, &p.Payload, &p.Attempts, &p.CreatedAt, &p.URL, &p.Secret); err != nil {
+			return nil, fmt.Errorf("failed to scan pending webhook: %w", err)
+		}
+		pending = append(pending, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed iterating pending webhooks: %w", err)
+	}
+
+	sort.Slice(pending, func(i, j int) bool { return pending[i].ID < pending[j].ID })
+	return pending, nil
+}
+
+func (s dbWebhookStore) recordWebhookSuccess(ctx context.Context, p pendingWebhook, statusCode int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE webhook_deliveries SET status = $1, attempts = attempts + 1, last_status_code = $2, last_error = '', delivered_at = CURRENT_TIMESTAMP WHERE id = $3",
+		webhookStatusDelivered, statusCode, p.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook %d as delivered: %w", p.ID, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE webhook_subscriptions SET consecutive_failures = 0 WHERE id = $1", p.SubscriptionID); err != nil {
+		return fmt.Errorf("failed to reset webhook subscription %d: %w", p.SubscriptionID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit webhook %d delivery: %w", p.ID, err)
+	}
+	return nil
+}
+
+func (s dbWebhookStore) recordWebhookFailure(ctx context.Context, p pendingWebhook, failure webhookFailure) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var lastStatusCode *int
+	if failure.StatusCode != 0 {
+		lastStatusCode = &failure.StatusCode
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE webhook_deliveries SET status = $1, attempts = $2, last_status_code = $3, last_error = $4, next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $5) WHERE id = $6",
+		failure.Status, failure.Attempts, lastStatusCode, failure.Err, failure.RetryIn.Seconds(), p.ID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record webhook %d failure: %w", p.ID, err)
+	}
+
+	var failures int
+	err = tx.QueryRowContext(ctx,
+		"UPDATE webhook_subscriptions SET consecutive_failures = consecutive_failures + 1 WHERE id = $1 RETURNING consecutive_failures",
+		p.SubscriptionID,
+	).Scan(&failures)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count webhook subscription %d failure: %w", p.SubscriptionID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit webhook %d failure: %w", p.ID, err)
+	}
+	return failures, nil
+}
+
+func (s dbWebhookStore) disableWebhookSubscription(ctx context.Context, subscriptionID int) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE webhook_subscriptions SET enabled = false, disabled_at = CURRENT_TIMESTAMP WHERE id = $1 AND enabled",
+		subscriptionID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to disable webhook subscription %d: %w", subscriptionID, err)
+	}
+	return nil
+}
+
+func (s dbWebhookStore) releaseWebhooks(ctx context.Context, ids []int64) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE webhook_deliveries SET next_attempt_at = CURRENT_TIMESTAMP WHERE id = ANY($1) AND status = $2",
+		pq.Array(ids), webhookStatusPending,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to release webhooks: %w", err)
+	}
+	return nil
+}
+
+// getWebhooks handles GET /webhooks - Lists every webhook subscription
+func getWebhooks(w http.ResponseWriter, r *http.Request) error {
+	rows, err := db.Query("SELECT " + webhookColumns + " FROM webhook_subscriptions ORDER BY id")
+	if err != nil {
+		return fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []WebhookSubscription{}
+	for rows.Next() {
+		var webhook WebhookSubscription
+		if err := scanWebhook(rows, &webhook); err != nil {
+			return fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed iterating webhooks: %w", err)
+	}
+
+	return NewResponseWriter(w).JSON(http.StatusOK, webhooks)
+}
+
+// createWebhook handles POST /webhooks - Registers a partner endpoint; the response is the only one showing the secret
+func createWebhook(w http.ResponseWriter, r *http.Request) error {
+	req, err := decodeWebhook(r, true)
+	if err != nil {
+		return err
+	}
+	enabled := req.Enabled == nil || *req.Enabled
+
+	var webhook WebhookSubscription
+	err = scanWebhook(db.QueryRow(
+		"INSERT INTO webhook_subscriptions (url, event_types, secret, enabled) VALUES ($1, $2, $3, $4) RETURNING "+webhookColumns,
+		req.URL, pq.Array(req.EventTypes), req.Secret, enabled,
+	), &webhook)
+	if err != nil {
+		return fmt.Errorf("failed to insert webhook: %w", err)
+	}
+	webhook.Secret = req.Secret
+
+	log.Printf("Created webhook id=%d url=%q events=%v", webhook.ID, webhook.URL, webhook.EventTypes)
+
+	return NewResponseWriter(w).JSON(http.StatusCreated, webhook)
+}
+
+// getWebhookByID handles GET /webhooks/{id} - Retrieves one webhook subscription
+func getWebhookByID(w http.ResponseWriter, r *http.Request) error {
+	id, err := webhookIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	var webhook WebhookSubscription
+	err = scanWebhook(db.QueryRow("SELECT "+webhookColumns+" FROM webhook_subscriptions WHERE id = $1", id), &webhook)
+	if err == sql.ErrNoRows {
+		return notFound("webhook", id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	return NewResponseWriter(w).JSON(http.StatusOK, webhook)
+}
+
+// updateWebhook handles PUT /webhooks/{id} - Changes a subscription; enabling it again resets its failure count
+func updateWebhook(w http.ResponseWriter, r *http.Request) error {
+	id, err := webhookIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	req, err := decodeWebhook(r, false)
+	if err != nil {
+		return err
+	}
+	enabled := req.Enabled == nil || *req.Enabled
+
+	var webhook WebhookSubscription
+	err = scanWebhook(db.QueryRow(`
+		UPDATE webhook_subscriptions
+		SET url = $1, event_types = $2, secret = COALESCE(NULLIF($3, ''), secret), enabled = $4,
+			consecutive_failures = CASE WHEN $4 AND NOT enabled THEN 0 ELSE consecutive_failures END,
+			disabled_at = CASE WHEN $4 THEN NULL ELSE COALESCE(disabled_at, CURRENT_TIMESTAMP) END
+		WHERE id = $5
+		RETURNING `+webhookColumns,
+		req.URL, pq.Array(req.EventTypes), req.Secret, enabled, id,
+	), &webhook)
+	if err == sql.ErrNoRows {
+		return notFound("webhook", id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+
+	return NewResponseWriter(w).JSON(http.StatusOK, webhook)
+}
+
+// deleteWebhook handles DELETE /webhooks/{id} - Removes a subscription and its delivery log
+func deleteWebhook(w http.ResponseWriter, r *http.Request) error {
+	id, err := webhookIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	result, err := db.Exec("DELETE FROM webhook_subscriptions WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return notFound("webhook", id)
+	}
+
+	log.Printf("Deleted webhook id=%d", id)
+
+	NewResponseWriter(w).NoContent()
+	return nil
+}
+
+// getWebhookDeliveries handles GET /webhooks/{id}/deliveries - Delivery log of a subscription, newest first,
+// optionally filtered by status
+func getWebhookDeliveries(w http.ResponseWriter, r *http.Request) error {
+	id, err := webhookIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	if err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM webhook_subscriptions WHERE id = $1)", id).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to get webhook: %w", err)
+	}
+	if !exists {
+		return notFound("webhook", id)
+	}
+
+	query := r.URL.Query()
+	limit := defaultOffersPageSize
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			return badRequest("invalid limit: expected a positive integer")
+		}
+		limit = min(parsed, maxOffersPageSize)
+	}
+
+	status := query.Get("status")
+	switch status {
+	case "", webhookStatusPending, webhookStatusDelivered, webhookStatusFailed:
+	default:
+		return badRequest("invalid status %q", status)
+	}
+
+	rows, err := db.Query(
+		"SELECT "+webhookDeliveryColumns+" FROM webhook_deliveries WHERE subscription_id = $1 AND ($2 = '' OR status = $2) ORDER BY id DESC LIMIT $3",
+		id, status, limit,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		var delivery WebhookDelivery
+		if err := scanWebhookDelivery(rows, &delivery); err != nil {
+			return fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed iterating webhook deliveries: %w", err)
+	}
+
+	return NewResponseWriter(w).JSON(http.StatusOK, deliveries)
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thomasrubini/polymove/common"
+)
+
+// memWebhookStore is a webhookStore in memory, with a clock the tests move forward.
+type memWebhookStore struct {
+	mu            sync.Mutex
+	now           time.Time
+	subscriptions map[int]*memWebhookSubscription
+	deliveries    []*memWebhookDelivery
+}
+
+type memWebhookSubscription struct {
+	url      string
+	secret   string
+	enabled  bool
+	failures int
+}
+
+type memWebhookDelivery struct {
+	delivery      WebhookDelivery
+	nextAttemptAt time.Time
+}
+
+func newMemWebhookStore() *memWebhookStore {
+	return &memWebhookStore{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), subscriptions: make(map[int]*memWebhookSubscription)}
+}
+
+func (s *memWebhookStore) subscribe(id int, url, secret string) {
+	s.subscriptions[id] = &memWebhookSubscription{url: url, secret: secret, enabled: true}
+}
+
+func (s *memWebhookStore) enqueue(subscriptionID int, eventType string, payload string) *memWebhookDelivery {
+	d := &memWebhookDelivery{
+		delivery: WebhookDelivery{
+			ID:             int64(len(s.deliveries) + 1),
+			SubscriptionID: subscriptionID,
+			EventType:      eventType,
+			Payload:        json.RawMessage(payload),
+			Status:         webhookStatusPending,
+			CreatedAt:      s.now.Format(time.RFC3339),
+		},
+		nextAttemptAt: s.now,
+	}
+	s.deliveries = append(s.deliveries, d)
+	return d
+}
+
+func (s *memWebhookStore) advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = s.now.Add(d)
+}
+
+func (s *memWebhookStore) claimWebhooks(ctx context.Context, limit int, lease time.Duration) ([]pendingWebhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pending []pendingWebhook
+	for _, d := range s.deliveries {
+		subscription := s.subscriptions[d.delivery.SubscriptionID]
+		if len(pending) == limit || d.delivery.Status != webhookStatusPending || !subscription.enabled || d.nextAttemptAt.After(s.now) {
+			continue
+		}
+		d.nextAttemptAt = s.now.Add(lease)
+		pending = append(pending, pendingWebhook{WebhookDelivery: d.delivery, URL: subscription.url, Secret: subscription.secret})
+	}
+	return pending, nil
+}
+
+func (s *memWebhookStore) find(id int64) *memWebhookDelivery {
+	for _, d := range s.deliveries {
+		if d.delivery.ID == id {
+			return d
+		}
+	}
+	return nil
+}
+
+func (s *memWebhookStore) recordWebhookSuccess(ctx context.Context, p pendingWebhook, statusCode int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.find(p.ID)
+	d.delivery.Status = webhookStatusDelivered
+	d.delivery.Attempts++
+	d.delivery.LastStatusCode = &statusCode
+	s.subscriptions[p.SubscriptionID].failures = 0
+	return nil
+}
+
+func (s *memWebhookStore) recordWebhookFailure(ctx context.Context, p pendingWebhook, failure webhookFailure) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.find(p.ID)
+	d.delivery.Status = failure.Status
+	d.delivery.Attempts = failure.Attempts
+	d.delivery.LastError = failure.Err
+	d.nextAttemptAt = s.now.Add(failure.RetryIn)
+	subscription := s.subscriptions[p.SubscriptionID]
+	subscription.failures++
+	return subscription.failures, nil
+}
+
+func (s *memWebhookStore) disableWebhookSubscription(ctx context.Context, subscriptionID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscriptions[subscriptionID].enabled = false
+	return nil
+}
+
+func (s *memWebhookStore) releaseWebhooks(ctx context.Context, ids []int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		s.find(id).nextAttemptAt = s.now
+	}
+	return nil
+}
+
+// webhookReceiver is a partner endpoint answering with the status codes of answers in turn, then 200.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	answers  []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+
+	status := http.StatusOK
+	if len(r.answers) > 0 {
+		status, r.answers = r.answers[0], r.answers[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (r *webhookReceiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+func dispatch(t *testing.T, store webhookStore, client *http.Client) int {
+	t.Helper()
+	sent, err := dispatchWebhooks(context.Background(), store, client)
+	if err != nil {
+		t.Fatalf("dispatchWebhooks: %v", err)
+	}
+	return sent
+}
+
+func TestDispatchWebhooksSignsDeliveries(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	const secret = "0123456789abcdef-secret"
+	store := newMemWebhookStore()
+	store.subscribe(1, server.URL, secret)
+	delivery := store.enqueue(1, common.RoutingKeyOfferCreated, `{"offer_id":42}`)
+
+	if sent := dispatch(t, store, server.Client()); sent != 1 {
+		t.Fatalf("sent %d deliveries, want 1", sent)
+	}
+	if receiver.count() != 1 {
+		t.Fatalf("receiver got %d requests, want 1", receiver.count())
+	}
+
+	req, body := receiver.requests[0], receiver.bodies[0]
+	if got, want := req.Header.Get(webhookSignatureHeader), signWebhookPayload(secret, body); !hmac.Equal([]byte(got), []byte(want)) {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	if got := req.Header.Get(webhookEventHeader); got != common.RoutingKeyOfferCreated {
+		t.Errorf("event header = %q, want %q", got, common.RoutingKeyOfferCreated)
+	}
+	if got := req.Header.Get(webhookDeliveryHeader); got != "1" {
+		t.Errorf("delivery header = %q, want 1", got)
+	}
+
+	var envelope webhookEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if envelope.ID != 1 || envelope.Type != common.RoutingKeyOfferCreated || string(envelope.Data) != `{"offer_id":42}` {
+		t.Errorf("envelope = %+v", envelope)
+	}
+	if delivery.delivery.Status != webhookStatusDelivered {
+		t.Errorf("status = %q, want %q", delivery.delivery.Status, webhookStatusDelivered)
+	}
+}
+
+func TestDispatchWebhooksRetriesWithBackoff(t *testing.T) {
+	receiver := &webhookReceiver{answers: []int{http.StatusInternalServerError, http.StatusServiceUnavailable}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	store := newMemWebhookStore()
+	store.subscribe(1, server.URL, "0123456789abcdef-secret")
+	delivery := store.enqueue(1, common.RoutingKeyOfferClosed, `{"offer_id":7}`)
+
+	dispatch(t, store, server.Client())
+	if delivery.delivery.Status != webhookStatusPending || delivery.delivery.Attempts != 1 {
+		t.Fatalf("after a failure: status %q attempts %d, want pending after 1 attempt", delivery.delivery.Status, delivery.delivery.Attempts)
+	}
+
+	// The retry waits for the backoff.
+	store.advance(webhookBackoff(1) - time.Second)
+	if sent := dispatch(t, store, server.Client()); sent != 0 {
+		t.Fatalf("retried %d deliveries before the backoff elapsed", sent)
+	}
+	store.advance(time.Second)
+	dispatch(t, store, server.Client())
+	if delivery.delivery.Attempts != 2 {
+		t.Fatalf("attempts = %d after the first backoff, want 2", delivery.delivery.Attempts)
+	}
+
+	// The backoff doubles after the second failure.
+	store.advance(webhookBackoff(1))
+	if sent := dispatch(t, store, server.Client()); sent != 0 {
+		t.Fatalf("retried %d deliveries before the doubled backoff elapsed", sent)
+	}
+	store.advance(webhookBackoff(2) - webhookBackoff(1))
+	dispatch(t, store, server.Client())
+
+	if delivery.delivery.Status != webhookStatusDelivered || delivery.delivery.Attempts != 3 {
+		t.Errorf("status %q attempts %d, want delivered after 3 attempts", delivery.delivery.Status, delivery.delivery.Attempts)
+	}
+	if receiver.count() != 3 {
+		t.Errorf("receiver got %d requests, want 3", receiver.count())
+	}
+}
+
+func TestDispatchWebhooksGivesUpAfterMaxAttempts(t *testing.T) {
+	answers := make([]int, webhookMaxAttempts)
+	for i := range answers {
+		answers[i] = http.StatusBadGateway
+	}
+	receiver := &webhookReceiver{answers: answers}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	store := newMemWebhookStore()
+	store.subscribe(1, server.URL, "0123456789abcdef-secret")
+	delivery := store.enqueue(1, common.RoutingKeyOfferUpdated, `{}`)
+
+	for i := 0; i < webhookMaxAttempts+2; i++ {
+		dispatch(t, store, server.Client())
+		store.advance(webhookMaxBackoff)
+	}
+
+	if delivery.delivery.Status != webhookStatusFailed || delivery.delivery.Attempts != webhookMaxAttempts {
+		t.Errorf("status %q attempts %d, want failed after %d attempts", delivery.delivery.Status, delivery.delivery.Attempts, webhookMaxAttempts)
+	}
+	if receiver.count() != webhookMaxAttempts {
+		t.Errorf("receiver got %d requests, want %d", receiver.count(), webhookMaxAttempts)
+	}
+}
+
+func TestDispatchWebhooksDisablesFailingSubscriptions(t *testing.T) {
+	answers := make([]int, 2*webhookDisableAfter)
+	for i := range answers {
+		answers[i] = http.StatusInternalServerError
+	}
+	receiver := &webhookReceiver{answers: answers}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	store := newMemWebhookStore()
+	store.subscribe(1, server.URL, "0123456789abcdef-secret")
+	for i := 0; i < webhookDisableAfter+2; i++ {
+		store.enqueue(1, common.RoutingKeyOfferCreated, `{}`)
+	}
+
+	dispatch(t, store, server.Client())
+
+	if store.subscriptions[1].enabled {
+		t.Fatalf("subscription still enabled after %d consecutive failures", webhookDisableAfter)
+	}
+	if receiver.count() != webhookDisableAfter {
+		t.Errorf("receiver got %d requests, want %d", receiver.count(), webhookDisableAfter)
+	}
+
+	// The deliveries left stay pending and are not sent while the subscription is disabled.
+	store.advance(webhookMaxBackoff)
+	if sent := dispatch(t, store, server.Client()); sent != 0 {
+		t.Errorf("sent %d deliveries to a disabled subscription", sent)
+	}
+	for _, d := range store.deliveries[webhookDisableAfter:] {
+		if d.delivery.Status != webhookStatusPending || d.delivery.Attempts != 0 {
+			t.Errorf("delivery %d: status %q attempts %d, want pending and never attempted", d.delivery.ID, d.delivery.Status, d.delivery.Attempts)
+		}
+	}
+}
+
+func TestDispatchWebhooksResetsFailuresOnSuccess(t *testing.T) {
+	answers := make([]int, webhookDisableAfter-1)
+	for i := range answers {
+		answers[i] = http.StatusInternalServerError
+	}
+	receiver := &webhookReceiver{answers: answers}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	store := newMemWebhookStore()
+	store.subscribe(1, server.URL, "0123456789abcdef-secret")
+	for i := 0; i < webhookDisableAfter+1; i++ {
+		store.enqueue(1, common.RoutingKeyOfferCreated, `{}`)
+	}
+
+	dispatch(t, store, server.Client())
+
+	if !store.subscriptions[1].enabled || store.subscriptions[1].failures != 0 {
+		t.Errorf("enabled %v with %d failures, want enabled with the failures reset", store.subscriptions[1].enabled, store.subscriptions[1].failures)
+	}
+}
@@ -0,0 +1,12 @@
+import { TOKEN_COOKIE } from '$lib/server/auth.js';
+
+const POLYTECH_BASE_URL = process.env.POLYTECH_BASE_URL || 'http://localhost:8080';
+
+// Forward the token of the logged-in user to Polytech, which authorizes every student route with it.
+export async function handleFetch({ event, request, fetch }) {
+	const token = event.cookies.get(TOKEN_COOKIE);
+	if (token && request.url.startsWith(POLYTECH_BASE_URL)) {
+		request.headers.set('Authorization', `Bearer ${token}`);
+	}
+	return fetch(request);
+}
@@ -0,0 +1,2 @@
+// TOKEN_COOKIE holds the bearer token returned by Polytech's POST /auth/login.
+export const TOKEN_COOKIE = 'polymove_token';
@@ -25,12 +25,16 @@ function parseErrorMessage(payload, fallback) {
 export async function load({ fetch, url }) {
 	const city = readTextParam(url, 'city');
 	const domain = readTextParam(url, 'domain');
+	const near = readTextParam(url, 'near');
+	const radiusKm = readTextParam(url, 'radius_km');
 	const studentId = readTextParam(url, 'student_id');
 	const limit = readLimit(url, 10);
 
 	const query = new URLSearchParams();
 	if (city) query.set('city', city);
 	if (domain) query.set('domain', domain);
+	if (near) query.set('near', near);
+	if (near && radiusKm) query.set('radius_km', radiusKm);
 	query.set('limit', String(limit));
 
 	let offers = [];
@@ -42,7 +46,8 @@ export async function load({ fetch, url }) {
 			const payload = await response.json().catch(() => ({}));
 			error = parseErrorMessage(payload, 'Failed to fetch offers');
 		} else {
-			offers = await response.json();
+			const payload = await response.json();
+			offers = payload.offers || [];
 		}
 	} catch {
 		error = 'Polytech API is unreachable.';
@@ -54,6 +59,8 @@ export async function load({ fetch, url }) {
 		filters: {
 			city,
 			domain,
+			near,
+			radiusKm,
 			limit: String(limit),
 			studentId
 		}
@@ -0,0 +1,62 @@
+import { fail, redirect } from '@sveltejs/kit';
+import { TOKEN_COOKIE } from '$lib/server/auth.js';
+
+const POLYTECH_BASE_URL = process.env.POLYTECH_BASE_URL || 'http://localhost:8080';
+
+function parseErrorMessage(payload, fallback) {
+	if (payload && typeof payload.message === 'string' && payload.message.length > 0) {
+		return payload.message;
+	}
+	if (payload && typeof payload.error === 'string' && payload.error.length > 0) {
+		return payload.error;
+	}
+	return fallback;
+}
+
+export function load({ cookies }) {
+	return { loggedIn: Boolean(cookies.get(TOKEN_COOKIE)) };
+}
+
+export const actions = {
+	login: async ({ fetch, request, cookies }) => {
+		const formData = await request.formData();
+		const email = String(formData.get('email') || '').trim();
+		const password = String(formData.get('password') || '');
+
+		if (!email || !password) {
+			return fail(400, { loginResult: { ok: false, message: 'Email and password are required.' } });
+		}
+
+		let payload;
+		try {
+			const response = await fetch(`${POLYTECH_BASE_URL}/auth/login`, {
+				method: 'POST',
+				headers: { 'Content-Type': 'application/json' },
+				body: JSON.stringify({ email, password })
+			});
+			payload = await response.json().catch(() => ({}));
+			if (!response.ok) {
+				return fail(response.status, {
+					loginResult: { ok: false, message: parseErrorMessage(payload, 'Login failed') }
+				});
+			}
+		} catch {
+			return fail(502, { loginResult: { ok: false, message: 'Polytech API is unreachable.' } });
+		}
+
+		cookies.set(TOKEN_COOKIE, payload.token, {
+			path: '/',
+			httpOnly: true,
+			sameSite: 'lax',
+			expires: new Date(payload.expires_at)
+		});
+
+		const studentId = payload.user?.student_id;
+		redirect(303, studentId ? `/dashboard?student_id=${studentId}` : '/');
+	},
+
+	logout: async ({ cookies }) => {
+		cookies.delete(TOKEN_COOKIE, { path: '/' });
+		redirect(303, '/login');
+	}
+};
@@ -49,8 +49,12 @@ func main() {
 	defer rmqChannel.Close()
 	defer rmqConn.Close()
 
-	go consumeStudentRegisteredEvents(rmqChannel)
-	go consumeOfferCreatedEvents(rmqChannel)
+	go common.ConsumeEvents(rmqChannel, common.QueueLaPosteStudentRegister, common.RoutingKeyStudentRegistered, processStudentRegisteredEvent)
+	go common.ConsumeEvents(rmqChannel, common.QueueLaPosteOfferCreated, common.RoutingKeyOfferCreated, processOfferCreatedEvent)
+	go common.ConsumeEvents(rmqChannel, common.QueueLaPosteOfferUpdated, common.RoutingKeyOfferUpdated, processOfferUpdatedEvent)
+	go common.ConsumeEvents(rmqChannel, common.QueueLaPosteOfferClosed, common.RoutingKeyOfferClosed, processOfferClosedEvent)
+	go common.ConsumeEvents(rmqChannel, common.QueueLaPosteOfferDeleted, common.RoutingKeyOfferDeleted, processOfferDeletedEvent)
+	go common.ConsumeEvents(rmqChannel, common.QueueLaPosteInternshipStatusChanged, common.RoutingKeyInternshipStatusChanged, processInternshipStatusChangedEvent)
 
 	router := mux.NewRouter()
 	router.HandleFunc("/subscribers/{studentId}", getSubscriber).Methods(http.MethodGet)
@@ -70,45 +74,6 @@ func initRabbitMQ() (*amqp.Connection, *amqp.Channel) {
 	)
 }
 
-// consumeStudentRegisteredEvents subscribes to student.registered and creates defaults.
-func consumeStudentRegisteredEvents(ch *amqp.Channel) {
-	queueName := common.QueueLaPosteStudentRegister
-	routingKey := common.RoutingKeyStudentRegistered
-
-	queue, err := ch.QueueDeclare(queueName, true, false, false, false, nil)
-	if err != nil {
-		log.Printf("Failed to declare queue: %v", err)
-		return
-	}
-
-	err = ch.QueueBind(queue.Name, routingKey, common.TopicExchange, false, nil)
-	if err != nil {
-		log.Printf("Failed to bind queue: %v", err)
-		return
-	}
-
-	deliveries, err := ch.Consume(queue.Name, "", false, false, false, false, nil)
-	if err != nil {
-		log.Printf("Failed to register consumer: %v", err)
-		return
-	}
-
-	log.Printf("Subscribed to routing key %s", routingKey)
-
-	for msg := range deliveries {
-		if err := processStudentRegisteredEvent(msg.Body); err != nil {
-			log.Printf("Failed to process student.registered event: %v", err)
-			if nackErr := msg.Nack(false, true); nackErr != nil {
-				log.Printf("Failed to nack message: %v", nackErr)
-			}
-			continue
-		}
-		if ackErr := msg.Ack(false); ackErr != nil {
-			log.Printf("Failed to ack message: %v", ackErr)
-		}
-	}
-}
-
 // processStudentRegisteredEvent stores default subscriber preferences for a student.
 func processStudentRegisteredEvent(payload []byte) error {
 	var event StudentRegisteredEvent
@@ -140,56 +105,137 @@ func processStudentRegisteredEvent(payload []byte) error {
 	return nil
 }
 
-// consumeOfferCreatedEvents subscribes to offer.created and dispatches matching alerts.
-func consumeOfferCreatedEvents(ch *amqp.Channel) {
-	queueName := common.QueueLaPosteOfferCreated
-	routingKey := common.RoutingKeyOfferCreated
+// processOfferCreatedEvent filters subscribers and sends alerts for matching offers.
+func processOfferCreatedEvent(payload []byte) error {
+	var event common.OfferCreatedEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal event: %w", err)
+	}
 
-	queue, err := ch.QueueDeclare(queueName, true, false, false, false, nil)
-	if err != nil {
-		log.Printf("Failed to declare queue: %v", err)
-		return
+	if event.Domain == "" || event.City == "" || event.OfferID <= 0 {
+		return fmt.Errorf("invalid offer.created event")
 	}
 
-	err = ch.QueueBind(queue.Name, routingKey, common.TopicExchange, false, nil)
-	if err != nil {
-		log.Printf("Failed to bind queue: %v", err)
-		return
+	dispatchOfferAlert(offerAlert{
+		Kind:     "new offer",
+		OfferID:  event.OfferID,
+		Title:    event.Title,
+		Employer: event.EmployerName,
+		City:     event.City,
+		Domain:   event.Domain,
+	})
+	return nil
+}
+
+// processOfferUpdatedEvent alerts matching subscribers that an offer changed.
+func processOfferUpdatedEvent(payload []byte) error {
+	var event common.OfferUpdatedEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal event: %w", err)
 	}
 
-	deliveries, err := ch.Consume(queue.Name, "", false, false, false, false, nil)
-	if err != nil {
-		log.Printf("Failed to register consumer: %v", err)
-		return
+	if event.Domain == "" || event.City == "" || event.OfferID <= 0 {
+		return fmt.Errorf("invalid offer.updated event")
 	}
 
-	log.Printf("Subscribed to routing key %s", routingKey)
+	kind := "updated offer"
+	if !event.Available {
+		kind = "unavailable offer"
+	}
 
-	for msg := range deliveries {
-		if err := processOfferCreatedEvent(msg.Body); err != nil {
-			log.Printf("Failed to process offer.created event: %v", err)
-			if nackErr := msg.Nack(false, true); nackErr != nil {
-				log.Printf("Failed to nack message: %v", nackErr)
-			}
-			continue
-		}
-		if ackErr := msg.Ack(false); ackErr != nil {
-			log.Printf("Failed to ack message: %v", ackErr)
-		}
+	dispatchOfferAlert(offerAlert{
+		Kind:     kind,
+		OfferID:  event.OfferID,
+		Title:    event.Title,
+		Employer: event.EmployerName,
+		City:     event.City,
+		Domain:   event.Domain,
+	})
+	return nil
+}
+
+// processOfferClosedEvent alerts matching subscribers that an offer was closed.
+func processOfferClosedEvent(payload []byte) error {
+	var event common.OfferClosedEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal event: %w", err)
+	}
+
+	if event.Domain == "" || event.OfferID <= 0 {
+		return fmt.Errorf("invalid offer.closed event")
 	}
+
+	dispatchOfferAlert(offerAlert{
+		Kind:    "closed offer",
+		OfferID: event.OfferID,
+		Title:   event.Title,
+		City:    event.City,
+		Domain:  event.Domain,
+	})
+	return nil
 }
 
-// processOfferCreatedEvent filters subscribers and sends alerts for matching offers.
-func processOfferCreatedEvent(payload []byte) error {
-	var event common.OfferCreatedEvent
+// processOfferDeletedEvent alerts matching subscribers that an offer was withdrawn.
+func processOfferDeletedEvent(payload []byte) error {
+	var event common.OfferDeletedEvent
 	if err := json.Unmarshal(payload, &event); err != nil {
 		return fmt.Errorf("failed to unmarshal event: %w", err)
 	}
 
-	if event.Domain == "" || event.City == "" || event.OfferID <= 0 {
-		return fmt.Errorf("invalid offer.created event")
+	if event.Domain == "" || event.OfferID <= 0 {
+		return fmt.Errorf("invalid offer.deleted event")
+	}
+
+	dispatchOfferAlert(offerAlert{
+		Kind:    "withdrawn offer",
+		OfferID: event.OfferID,
+		Title:   event.Title,
+		City:    event.City,
+		Domain:  event.Domain,
+	})
+	return nil
+}
+
+// processInternshipStatusChangedEvent tells the student of an internship that its status changed.
+func processInternshipStatusChangedEvent(payload []byte) error {
+	var event common.InternshipStatusChangedEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal event: %w", err)
+	}
+
+	if event.InternshipID <= 0 || event.StudentID <= 0 || event.To == "" {
+		return fmt.Errorf("invalid internship.status_changed event")
 	}
 
+	subscribersMu.RLock()
+	subscriber, exists := subscribers[event.StudentID]
+	subscribersMu.RUnlock()
+	if !exists || !subscriber.Enabled || subscriber.Contact == "" {
+		return nil
+	}
+
+	switch subscriber.Channel {
+	case "email", "sms":
+		log.Printf("Alert sent via %s to student=%d contact=%s: internship id=%d offer=%d moved from %s to %s reason=%q",
+			subscriber.Channel, subscriber.StudentID, subscriber.Contact, event.InternshipID, event.OfferID, event.From, event.To, event.Reason)
+	default:
+		log.Printf("Skipping alert for student=%d: unsupported channel=%s", subscriber.StudentID, subscriber.Channel)
+	}
+	return nil
+}
+
+// offerAlert describes one offer lifecycle change to relay to subscribers.
+type offerAlert struct {
+	Kind     string
+	OfferID  int
+	Title    string
+	Employer string
+	City     string
+	Domain   string
+}
+
+// dispatchOfferAlert sends an alert to every enabled subscriber following the offer domain.
+func dispatchOfferAlert(alert offerAlert) {
 	subscribersMu.RLock()
 	matchingSubscribers := make([]Subscriber, 0)
 	for _, subscriber := range subscribers {
@@ -199,7 +245,7 @@ func processOfferCreatedEvent(payload []byte) error {
 		if subscriber.Contact == "" {
 			continue
 		}
-		if subscriber.Domain != event.Domain {
+		if subscriber.Domain != alert.Domain {
 			continue
 		}
 		matchingSubscribers = append(matchingSubscribers, subscriber)
@@ -207,18 +253,16 @@ func processOfferCreatedEvent(payload []byte) error {
 	subscribersMu.RUnlock()
 
 	for _, subscriber := range matchingSubscribers {
-		sendOfferAlert(subscriber, event)
+		sendOfferAlert(subscriber, alert)
 	}
-
-	return nil
 }
 
 // sendOfferAlert emits an alert log for one subscriber and skips unsupported channels.
-func sendOfferAlert(subscriber Subscriber, event common.OfferCreatedEvent) {
+func sendOfferAlert(subscriber Subscriber, alert offerAlert) {
 	switch subscriber.Channel {
 	case "email", "sms":
-		log.Printf("Alert sent via %s to student=%d contact=%s: new offer id=%d title=%q city=%s domain=%s",
-			subscriber.Channel, subscriber.StudentID, subscriber.Contact, event.OfferID, event.Title, event.City, event.Domain)
+		log.Printf("Alert sent via %s to student=%d contact=%s: %s id=%d title=%q employer=%q city=%s domain=%s",
+			subscriber.Channel, subscriber.StudentID, subscriber.Contact, alert.Kind, alert.OfferID, alert.Title, alert.Employer, alert.City, alert.Domain)
 	default:
 		log.Printf("Skipping alert for student=%d: unsupported channel=%s", subscriber.StudentID, subscriber.Channel)
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"strconv"
 	"strings"
 	"time"
@@ -182,8 +183,41 @@ func processNewsEvent(ctx context.Context, payload []byte) error {
 		return fmt.Errorf("invalid news event: city and title are required")
 	}
 
-	_, err := createNewsRecord(ctx, event.City, event.Title, event.Content, event.Tags)
-	return err
+	if _, err := createNewsRecord(ctx, event.City, event.Title, event.Content, event.Tags); err != nil {
+		return err
+	}
+
+	publishCityScoreChanged(ctx, event.City)
+	return nil
+}
+
+// publishCityScoreChanged tells score caches, such as Polytech's, that a city's scores changed. Failures are only
+// logged: the news is stored, and caches expire on their own.
+func publishCityScoreChanged(ctx context.Context, city string) {
+	score, err := getScoreFromRedis(ctx, city)
+	if err != nil {
+		log.Printf("Failed to read scores of city=%s for city_score_changed: %v", city, err)
+		return
+	}
+
+	event := common.CityScoreChangedEvent{
+		City: city,
+		Scores: common.CityScore{
+			City:      city,
+			Safety:    score.Safety,
+			Economy:   score.Economy,
+			QoL:       score.Qol,
+			Culture:   score.Culture,
+			Relevance: score.Relevance,
+		},
+		ChangedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	eventMu.Lock()
+	defer eventMu.Unlock()
+	if err := common.PublishEvent(ctx, eventChannel, common.RoutingKeyCityScoreChanged, event); err != nil {
+		log.Printf("Failed to publish city_score_changed for city=%s: %v", city, err)
+	}
 }
 
 // processOfferCreatedEvent validates and stores city offer counters from a RabbitMQ payload.
@@ -247,18 +281,155 @@ func createNewsRecord(ctx context.Context, city, title, content string, tags []s
 
 // updateCityOfferStats increments per-city and per-domain offer counters.
 func updateCityOfferStats(ctx context.Context, event common.OfferCreatedEvent) error {
-	cityStatsKey := "city_offer_stats:" + event.City
-	cityDomainStatsKey := "city_offer_stats_domain:" + event.City
+	tracked, err := rdb.Exists(ctx, offerStatsEntryKey(event.OfferID)).Result()
+	if err != nil {
+		return err
+	}
+	if tracked > 0 {
+		// Redelivered event: the offer is already counted.
+		return nil
+	}
+
+	return trackOfferStats(ctx, event.OfferID, event.City, event.Domain, event.CreatedAt)
+}
+
+// offerStatsEntryKey is the Redis hash remembering where an offer is counted. Its counted field is "0" once the
+// offer was removed from the counters; entries written before that field existed are counted.
+func offerStatsEntryKey(offerID int) string {
+	return fmt.Sprintf("offer_stats_entry:%d", offerID)
+}
+
+// trackOfferStats counts one offer in its city and domain and remembers where it was counted.
+func trackOfferStats(ctx context.Context, offerID int, city, domain, date string) error {
+	cityStatsKey := "city_offer_stats:" + city
+	cityDomainStatsKey := "city_offer_stats_domain:" + city
 
 	pipe := rdb.TxPipeline()
 	pipe.HIncrBy(ctx, cityStatsKey, "total_offers", 1)
-	pipe.HSet(ctx, cityStatsKey, "city", event.City)
-	pipe.HSet(ctx, cityStatsKey, "last_offer_date", event.CreatedAt)
-	pipe.HIncrBy(ctx, cityDomainStatsKey, event.Domain, 1)
+	pipe.HSet(ctx, cityStatsKey, "city", city)
+	pipe.HSet(ctx, cityStatsKey, "last_offer_date", date)
+	pipe.HIncrBy(ctx, cityDomainStatsKey, domain, 1)
+	pipe.HSet(ctx, offerStatsEntryKey(offerID), "city", city, "domain", domain, "counted", "1")
 	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// untrackOfferStats removes an offer from the counters it was added to, if any. The entry is kept, marked as no
+// longer counted, so a later update can tell the offer apart from one counted before entries were kept.
+func untrackOfferStats(ctx context.Context, offerID int) error {
+	entry, err := rdb.HGetAll(ctx, offerStatsEntryKey(offerID)).Result()
 	if err != nil {
 		return err
 	}
+	if len(entry) == 0 || !offerStatsCounted(entry) {
+		return nil
+	}
+
+	pipe := rdb.TxPipeline()
+	pipe.HIncrBy(ctx, "city_offer_stats:"+entry["city"], "total_offers", -1)
+	pipe.HIncrBy(ctx, "city_offer_stats_domain:"+entry["city"], entry["domain"], -1)
+	pipe.HSet(ctx, offerStatsEntryKey(offerID), "counted", "0")
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func offerStatsCounted(entry map[string]string) bool {
+	return entry["counted"] != "0"
+}
+
+// adoptLegacyOfferStats writes the entry of an offer counted by offer.created before entries were kept, in the
+// city and domain it had before event, and returns it.
+func adoptLegacyOfferStats(ctx context.Context, event common.OfferUpdatedEvent) (map[string]string, error) {
+	city, domain := event.City, event.Domain
+	for _, change := range event.Changes {
+		if from, ok := change.From.(string); ok {
+			switch change.Field {
+			case "city":
+				city = from
+			case "domain":
+				domain = from
+			}
+		}
+	}
+	return adoptOfferStats(ctx, event.OfferID, city, domain)
+}
+
+// adoptOfferStats writes the entry of an offer without one, counted in city and domain. Offers counted by
+// offer.created before entries were kept have none, and are still counted whatever happened to them since.
+func adoptOfferStats(ctx context.Context, offerID int, city, domain string) (map[string]string, error) {
+	entry := map[string]string{"city": city, "domain": domain, "counted": "1"}
+	err := rdb.HSet(ctx, offerStatsEntryKey(offerID), "city", city, "domain", domain, "counted", "1").Err()
+	return entry, err
+}
+
+// processOfferUpdatedEvent moves counters when an offer changes city or domain, is reopened or made unavailable.
+func processOfferUpdatedEvent(ctx context.Context, payload []byte) error {
+	var event common.OfferUpdatedEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal offer event: %w", err)
+	}
+
+	if event.City == "" || event.Domain == "" || event.OfferID <= 0 {
+		return fmt.Errorf("invalid offer.updated event")
+	}
+
+	entry, err := rdb.HGetAll(ctx, offerStatsEntryKey(event.OfferID)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to load offer stats entry: %w", err)
+	}
+
+	if len(entry) == 0 {
+		if entry, err = adoptLegacyOfferStats(ctx, event); err != nil {
+			return fmt.Errorf("failed to adopt offer stats entry: %w", err)
+		}
+	}
+
+	tracked := offerStatsCounted(entry)
+	moved := tracked && (entry["city"] != event.City || entry["domain"] != event.Domain)
+
+	if tracked && (!event.Available || moved) {
+		if err := untrackOfferStats(ctx, event.OfferID); err != nil {
+			return fmt.Errorf("failed to update city stats: %w", err)
+		}
+	}
+	if event.Available && (!tracked || moved) {
+		if err := trackOfferStats(ctx, event.OfferID, event.City, event.Domain, event.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to update city stats: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// processOfferRemovedEvent decrements counters for closed, deleted or expired offers; offers without an entry are
+// taken out of the city and domain the event names.
+func processOfferRemovedEvent(ctx context.Context, payload []byte) error {
+	var event struct {
+		OfferID int    `json:"offer_id"`
+		City    string `json:"city"`
+		Domain  string `json:"domain"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal offer event: %w", err)
+	}
+
+	if event.OfferID <= 0 {
+		return fmt.Errorf("invalid offer event: offer_id is required")
+	}
+
+	tracked, err := rdb.Exists(ctx, offerStatsEntryKey(event.OfferID)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to load offer stats entry: %w", err)
+	}
+	if tracked == 0 && event.City != "" && event.Domain != "" {
+		if _, err := adoptOfferStats(ctx, event.OfferID, event.City, event.Domain); err != nil {
+			return fmt.Errorf("failed to adopt offer stats entry: %w", err)
+		}
+	}
+
+	if err := untrackOfferStats(ctx, event.OfferID); err != nil {
+		return fmt.Errorf("failed to update city stats: %w", err)
+	}
 
 	return nil
 }
@@ -282,7 +453,7 @@ func getCityStatsFromRedis(ctx context.Context, city string) (*proto.CityStats,
 	offersByDomain := make(map[string]int32, len(domainCounts))
 	for domain, countRaw := range domainCounts {
 		count, parseErr := strconv.Atoi(countRaw)
-		if parseErr != nil {
+		if parseErr != nil || count <= 0 {
 			continue
 		}
 		offersByDomain[domain] = int32(count)
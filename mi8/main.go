@@ -5,6 +5,7 @@ import (
 	"log"
 	"net"
 	"os"
+	"sync"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
@@ -17,6 +18,12 @@ import (
 var rdb *redis.Client
 var ctx = context.Background()
 
+// eventChannel publishes city_score_changed events; consumers share rmqChannel, so publishing gets its own.
+var (
+	eventChannel *amqp.Channel
+	eventMu      sync.Mutex
+)
+
 func main() {
 	log.SetOutput(os.Stdout)
 
@@ -24,9 +31,31 @@ func main() {
 	rmqConn, rmqChannel := initRabbitMQ()
 	defer rmqChannel.Close()
 	defer rmqConn.Close()
+	var err error
+	eventChannel, err = rmqConn.Channel()
+	if err != nil {
+		log.Fatalf("Failed to open event channel: %v", err)
+	}
+	defer eventChannel.Close()
 
-	go consumeNewsEvents(rmqChannel)
-	go consumeOfferCreatedEvents(rmqChannel)
+	go common.ConsumeEvents(rmqChannel, common.QueueMI8News, common.RoutingKeyMI8News, func(payload []byte) error {
+		return processNewsEvent(ctx, payload)
+	})
+	go common.ConsumeEvents(rmqChannel, common.QueueMI8OfferCreated, common.RoutingKeyOfferCreated, func(payload []byte) error {
+		return processOfferCreatedEvent(ctx, payload)
+	})
+	go common.ConsumeEvents(rmqChannel, common.QueueMI8OfferUpdated, common.RoutingKeyOfferUpdated, func(payload []byte) error {
+		return processOfferUpdatedEvent(ctx, payload)
+	})
+	go common.ConsumeEvents(rmqChannel, common.QueueMI8OfferClosed, common.RoutingKeyOfferClosed, func(payload []byte) error {
+		return processOfferRemovedEvent(ctx, payload)
+	})
+	go common.ConsumeEvents(rmqChannel, common.QueueMI8OfferDeleted, common.RoutingKeyOfferDeleted, func(payload []byte) error {
+		return processOfferRemovedEvent(ctx, payload)
+	})
+	go common.ConsumeEvents(rmqChannel, common.QueueMI8OfferExpired, common.RoutingKeyOfferExpired, func(payload []byte) error {
+		return processOfferRemovedEvent(ctx, payload)
+	})
 
 	lis, err := net.Listen("tcp", ":8082")
 	if err != nil {
@@ -50,107 +79,6 @@ func initRabbitMQ() (*amqp.Connection, *amqp.Channel) {
 	)
 }
 
-// consumeNewsEvents subscribes to mi8.news and stores each event in Redis.
-func consumeNewsEvents(ch *amqp.Channel) {
-	queueName := common.QueueMI8News
-	routingKey := common.RoutingKeyMI8News
-
-	queue, err := ch.QueueDeclare(
-		queueName,
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
-	if err != nil {
-		log.Printf("Failed to declare queue: %v", err)
-		return
-	}
-
-	err = ch.QueueBind(
-		queue.Name,
-		routingKey,
-		common.TopicExchange,
-		false,
-		nil,
-	)
-	if err != nil {
-		log.Printf("Failed to bind queue: %v", err)
-		return
-	}
-
-	deliveries, err := ch.Consume(
-		queue.Name,
-		"",
-		false,
-		false,
-		false,
-		false,
-		nil,
-	)
-	if err != nil {
-		log.Printf("Failed to register consumer: %v", err)
-		return
-	}
-
-	log.Printf("Subscribed to RabbitMQ topic %s", routingKey)
-
-	for msg := range deliveries {
-		if err := processNewsEvent(ctx, msg.Body); err != nil {
-			log.Printf("Failed to process news event: %v", err)
-			if nackErr := msg.Nack(false, true); nackErr != nil {
-				log.Printf("Failed to nack message: %v", nackErr)
-			}
-			continue
-		}
-
-		if ackErr := msg.Ack(false); ackErr != nil {
-			log.Printf("Failed to ack message: %v", ackErr)
-		}
-	}
-}
-
-// consumeOfferCreatedEvents subscribes to offer.created and updates city offer statistics.
-func consumeOfferCreatedEvents(ch *amqp.Channel) {
-	queueName := common.QueueMI8OfferCreated
-	routingKey := common.RoutingKeyOfferCreated
-
-	queue, err := ch.QueueDeclare(queueName, true, false, false, false, nil)
-	if err != nil {
-		log.Printf("Failed to declare queue: %v", err)
-		return
-	}
-
-	err = ch.QueueBind(queue.Name, routingKey, common.TopicExchange, false, nil)
-	if err != nil {
-		log.Printf("Failed to bind queue: %v", err)
-		return
-	}
-
-	deliveries, err := ch.Consume(queue.Name, "", false, false, false, false, nil)
-	if err != nil {
-		log.Printf("Failed to register consumer: %v", err)
-		return
-	}
-
-	log.Printf("Subscribed to RabbitMQ topic %s", routingKey)
-
-	for msg := range deliveries {
-		if err := processOfferCreatedEvent(ctx, msg.Body); err != nil {
-			log.Printf("Failed to process offer.created event: %v", err)
-			if nackErr := msg.Nack(false, true); nackErr != nil {
-				log.Printf("Failed to nack message: %v", nackErr)
-			}
-			continue
-		}
-
-		if ackErr := msg.Ack(false); ackErr != nil {
-			log.Printf("Failed to ack message: %v", ackErr)
-		}
-	}
-}
-
 func initRedis() {
 	host := getEnv("REDIS_HOST", "redis")
 	rdb = redis.NewClient(&redis.Options{
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/thomasrubini/polymove/common/apierror"
+	"github.com/thomasrubini/polymove/common/auth"
+)
+
+const (
+	// tokenTTL is how long a token issued by POST /auth/login stays valid.
+	tokenTTL = 12 * time.Hour
+	// serviceTokenTTL is how long the token Polytech calls Erasmumu with stays valid; it is renewed a minute early.
+	serviceTokenTTL = 15 * time.Minute
+	// serviceName identifies Polytech in service tokens, and so in Erasmumu's audit history.
+	serviceName = "polytech"
+)
+
+// jwtSecret signs and verifies tokens; it is shared with Erasmumu, see auth.SecretFromEnv.
+var jwtSecret []byte
+
+// serviceTokens caches the current service token, see serviceToken.
+var serviceTokens struct {
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// dummyPasswordHash is checked against when a login names an unknown email, so it takes as long as a wrong password.
+var dummyPasswordHash, _ = auth.HashPassword("polymove")
+
+// User is an account allowed to log in.
+type User struct {
+	ID         int    `json:"id"`
+	Email      string `json:"email"`
+	Role       string `json:"role"`
+	StudentID  *int   `json:"student_id,omitempty"`
+	EmployerID *int   `json:"employer_id,omitempty"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// UserRequest is the body of POST /users.
+type UserRequest struct {
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	Role       string `json:"role"`
+	StudentID  *int   `json:"student_id"`
+	EmployerID *int   `json:"employer_id"`
+}
+
+// LoginRequest is the body of POST /auth/login.
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// LoginResponse carries the bearer token to send as "Authorization: Bearer <token>".
+type LoginResponse struct {
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expires_at"`
+	User      User   `json:"user"`
+}
+
+// userColumns lists the users columns in the order expected by scanUser.
+const userColumns = "id, email, role, student_id, employer_id, TO_CHAR(created_at, 'YYYY-MM-DD\"T\"HH24:MI:SS\"Z\"')"
+
+func scanUser(row interface{ Scan(...interface{}) error }, user *User) error {
+	var studentID, employerID sql.NullInt64
+	if err := row.Scan(&user.ID, &user.Email, &user.Role, &studentID, &employerID, &user.CreatedAt); err != nil {
+		return err
+	}
+	user.StudentID, user.EmployerID = nullableInt(studentID), nullableInt(employerID)
+	return nil
+}
+
+func nullableInt(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+	id := int(value.Int64)
+	return &id
+}
+
+func (u User) claims() auth.Claims {
+	claims := auth.Claims{UserID: u.ID, Email: u.Email, Role: u.Role}
+	if u.StudentID != nil {
+		claims.StudentID = *u.StudentID
+	}
+	if u.EmployerID != nil {
+		claims.EmployerID = *u.EmployerID
+	}
+	return claims
+}
+
+// authenticate verifies the bearer token of a request.
+func authenticate(r *http.Request) (auth.Claims, error) {
+	token, err := auth.BearerToken(r)
+	if err != nil {
+		return auth.Claims{}, apierror.Unauthorized("missing_token", "%v", err)
+	}
+	claims, err := auth.Verify(jwtSecret, token, time.Now())
+	if errors.Is(err, auth.ErrExpiredToken) {
+		return auth.Claims{}, apierror.Unauthorized("token_expired", "%v", err)
+	}
+	if err != nil {
+		return auth.Claims{}, apierror.Unauthorized("invalid_token", "%v", err)
+	}
+	return claims, nil
+}
+
+// requireRole lets through requests authenticated with one of roles; the claims are stored in the request context.
+func requireRole(fn func(w http.ResponseWriter, r *http.Request) error, roles ...string) func(w http.ResponseWriter, r *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		claims, err := authenticate(r)
+		if err != nil {
+			return err
+		}
+		if !claims.HasRole(roles...) {
+			return apierror.Forbidden("forbidden_role", "%s users may not %s %s", claims.Role, r.Method, r.URL.Path)
+		}
+		return fn(w, r.WithContext(auth.WithClaims(r.Context(), claims)))
+	}
+}
+
+// requireStudentOrAdmin lets through admins and the student whose id is the {id} route variable.
+func requireStudentOrAdmin(fn func(w http.ResponseWriter, r *http.Request) error) func(w http.ResponseWriter, r *http.Request) error {
+	return requireRole(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := studentIDFromRequest(r)
+		if err != nil {
+			return err
+		}
+		if err := authorizeStudent(r.Context(), id); err != nil {
+			return err
+		}
+		return fn(w, r)
+	}, auth.RoleStudent, auth.RoleAdmin)
+}
+
+// authorizeStudent checks that the caller may act for a student: admins may act for anyone, students for themselves.
+func authorizeStudent(ctx context.Context, studentID int) error {
+	claims, ok := auth.FromContext(ctx)
+	if !ok {
+		return apierror.Unauthorized("missing_token", "authentication required")
+	}
+	if claims.Role == auth.RoleAdmin || (claims.Role == auth.RoleStudent && claims.StudentID == studentID) {
+		return nil
+	}
+	return apierror.Forbidden("forbidden_student", "not allowed to act for student %d", studentID)
+}
+
+// studentScope is the student a caller is restricted to, or 0 for admins.
+func studentScope(ctx context.Context) int {
+	claims, _ := auth.FromContext(ctx)
+	if claims.Role == auth.RoleAdmin {
+		return 0
+	}
+	return claims.StudentID
+}
+
+// authorizeInternship checks that the caller may act for the student of an internship.
+func authorizeInternship(ctx context.Context, id int) error {
+	var studentID int
+	err := db.QueryRowContext(ctx, "SELECT student_id FROM internships WHERE id = $1", id).Scan(&studentID)
+	if err == sql.ErrNoRows {
+		return internshipNotFound(id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get internship: %w", err)
+	}
+	return authorizeStudent(ctx, studentID)
+}
+
+// serviceToken returns a token identifying Polytech itself, for the Erasmumu calls it makes on no user's behalf.
+func serviceToken() (string, error) {
+	serviceTokens.mu.Lock()
+	defer serviceTokens.mu.Unlock()
+
+	if time.Until(serviceTokens.expiresAt) > time.Minute {
+		return serviceTokens.token, nil
+	}
+	token, expiresAt, err := auth.IssueService(jwtSecret, serviceName, serviceTokenTTL)
+	if err != nil {
+		return "", err
+	}
+	serviceTokens.token, serviceTokens.expiresAt = token, expiresAt
+	return token, nil
+}
+
+// login handles POST /auth/login - Exchanges an email and password for a bearer token
+func login(w http.ResponseWriter, r *http.Request) error {
+	var req LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apierror.Validation("invalid_body", "failed to decode request body: %v", err)
+	}
+
+	// Password checks are slow on purpose: lock emails under guessing and cap the checks running at once.
+	key := loginThrottleKey(req.Email)
+	if wait := failedLogins.retryAfter(key, time.Now()); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		return apierror.TooManyRequests("login_locked", "too many failed logins, retry in %s", wait.Round(time.Second))
+	}
+	if err := acquireLoginSlot(r.Context()); err != nil {
+		return err
+	}
+	defer releaseLoginSlot()
+
+	var (
+		user                  User
+		passwordHash          string
+		studentID, employerID sql.NullInt64
+	)
+	err := db.QueryRow(
+		"SELECT "+userColumns+", password_hash FROM users WHERE LOWER(email) = LOWER($1)",
+		strings.TrimSpace(req.Email),
+	).Scan(&user.ID, &user.Email, &user.Role, &studentID, &employerID, &user.CreatedAt, &passwordHash)
+	if err == sql.ErrNoRows {
+		auth.CheckPassword(dummyPasswordHash, req.Password)
+		failedLogins.fail(key, time.Now())
+		return apierror.Unauthorized("invalid_credentials", "invalid email or password")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if !auth.CheckPassword(passwordHash, req.Password) {
+		failedLogins.fail(key, time.Now())
+		return apierror.Unauthorized("invalid_credentials", "invalid email or password")
+	}
+	failedLogins.succeed(key)
+	user.StudentID, user.EmployerID = nullableInt(studentID), nullableInt(employerID)
+
+	token, expiresAt, err := auth.Issue(jwtSecret, user.claims(), tokenTTL)
+	if err != nil {
+		return err
+	}
+
+	return NewResponseWriter(w).JSON(http.StatusOK, LoginResponse{
+		Token:     token,
+		ExpiresAt: expiresAt.Format(time.RFC3339),
+		User:      user,
+	})
+}
+
+// getCurrentUser handles GET /auth/me - Returns the account of the token
+func getCurrentUser(w http.ResponseWriter, r *http.Request) error {
+	claims, _ := auth.FromContext(r.Context())
+
+	var user User
+	err := scanUser(db.QueryRow("SELECT "+userColumns+" FROM users WHERE id = $1", claims.UserID), &user)
+	if err == sql.ErrNoRows {
+		return apierror.Unauthorized("invalid_token", "user %d no longer exists", claims.UserID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return NewResponseWriter(w).JSON(http.StatusOK, user)
+}
+
+// createUser handles POST /users - Creates an account
+func createUser(w http.ResponseWriter, r *http.Request) error {
+	var req UserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apierror.Validation("invalid_body", "failed to decode request body: %v", err)
+	}
+
+	user, err := insertUser(req)
+	if err != nil {
+		return err
+	}
+
+	return NewResponseWriter(w).JSON(http.StatusCreated, user)
+}
+
+// insertUser validates and stores a new account.
+func insertUser(req UserRequest) (User, error) {
+	req.Email = strings.TrimSpace(req.Email)
+	switch {
+	case !strings.Contains(req.Email, "@"):
+		return User{}, apierror.Validation("invalid_user", "email must be an email address")
+	case len(req.Password) < auth.MinPasswordLength:
+		return User{}, apierror.Validation("invalid_user", "password must be at least %d characters", auth.MinPasswordLength)
+	case !auth.ValidRole(req.Role):
+		return User{}, apierror.Validation("invalid_user", "role must be %s, %s or %s", auth.RoleStudent, auth.RoleAdmin, auth.RoleEmployer)
+	case req.Role == auth.RoleStudent && req.StudentID == nil:
+		return User{}, apierror.Validation("invalid_user", "student_id is required for students")
+	case req.Role == auth.RoleEmployer && req.EmployerID == nil:
+		return User{}, apierror.Validation("invalid_user", "employer_id is required for employers")
+	}
+	if req.Role != auth.RoleStudent {
+		req.StudentID = nil
+	}
+	if req.Role != auth.RoleEmployer {
+		req.EmployerID = nil
+	}
+
+	passwordHash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		return User{}, err
+	}
+
+	var user User
+	err = scanUser(db.QueryRow(
+		"INSERT INTO users (email, password_hash, role, student_id, employer_id) VALUES ($1, $2, $3, $4, $5) RETURNING "+userColumns,
+		req.Email, passwordHash, req.Role, req.StudentID, req.EmployerID,
+	), &user)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch pqErr.Code {
+			case "23505":
+				return User{}, apierror.Conflict("email_taken", "a user with email %s already exists", req.Email)
+			case "23503":
+				return User{}, studentNotFound(*req.StudentID)
+			}
+		}
+		return User{}, fmt.Errorf("failed to create user: %w", err)
+	}
+	return user, nil
+}
+
+// bootstrapAdmin creates the ADMIN_EMAIL account with ADMIN_PASSWORD when both are set and no such user exists,
+// so a fresh deployment has someone to create the other accounts.
+func bootstrapAdmin() {
+	email, password := getEnv("ADMIN_EMAIL", ""), getEnv("ADMIN_PASSWORD", "")
+	if email == "" || password == "" {
+		return
+	}
+
+	var exists bool
+	if err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(email) = LOWER($1))", email).Scan(&exists); err != nil {
+		log.Fatalf("Failed to look up admin user: %v", err)
+	}
+	if exists {
+		return
+	}
+
+	if _, err := insertUser(UserRequest{Email: email, Password: password, Role: auth.RoleAdmin}); err != nil {
+		log.Fatalf("Failed to create admin user: %v", err)
+	}
+	log.Printf("Created admin user %s", email)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// cityCacheTTL is how long cached city intelligence is served without asking MI8 again.
+	cityCacheTTL = 10 * time.Minute
+	// cityCacheMaxStale is how long past its TTL an entry is still served while it is refreshed in the
+	// background; older entries are refreshed before answering, and only served if MI8 is unavailable.
+	cityCacheMaxStale = time.Hour
+)
+
+// cityCache holds the city intelligence of every city offers were enriched with.
+var cityCache = newCityIntelligenceCache(cityCacheTTL, cityCacheMaxStale)
+
+type cityCacheEntry struct {
+	intel     cityIntelligence
+	fetchedAt time.Time
+	// invalidated is set by city_score_changed: the entry is only kept as a fallback for MI8 outages.
+	invalidated bool
+	refreshing  bool
+}
+
+// cityIntelligenceCache caches cityIntelligence by city with stale-while-revalidate: fresh entries are served
+// as they are, stale ones are served while one background refresh updates them, and when MI8 cannot answer
+// the last known intelligence is served instead of none. Concurrent misses of a city share one MI8 load.
+type cityIntelligenceCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	maxStale time.Duration
+	entries  map[string]*cityCacheEntry
+	metrics  CityCacheMetrics
+
+	// generations counts the invalidations of each city: a load started before the latest one may return
+	// scores older than the change, so it is not cached.
+	generations map[string]uint64
+	loads       singleflight.Group
+}
+
+// CityCacheMetrics is the body of GET /metrics/city-cache. StaleHits were served while refreshing; Fallbacks
+// count the times MI8 failed and the last known intelligence was kept.
+type CityCacheMetrics struct {
+	Entries         int   `json:"entries"`
+	Hits            int64 `json:"hits"`
+	StaleHits       int64 `json:"stale_hits"`
+	Misses          int64 `json:"misses"`
+	Fallbacks       int64 `json:"fallbacks"`
+	Invalidations   int64 `json:"invalidations"`
+	RefreshFailures int64 `json:"refresh_failures"`
+}
+
+func newCityIntelligenceCache(ttl, maxStale time.Duration) *cityIntelligenceCache {
+	return &cityIntelligenceCache{
+		ttl:         ttl,
+		maxStale:    maxStale,
+		entries:     make(map[string]*cityCacheEntry),
+		generations: make(map[string]uint64),
+	}
+}
+
+func cityCacheKey(city string) string {
+	return strings.ToLower(strings.TrimSpace(city))
+}
+
+// get returns the intelligence of city, asking MI8 only when the cached entry is missing, invalidated or too old.
+func (c *cityIntelligenceCache) get(ctx context.Context, city string) cityIntelligence {
+	key := cityCacheKey(city)
+	now := time.Now()
+
+	c.mu.Lock()
+	generation := c.generations[key]
+	entry, ok := c.entries[key]
+	switch {
+	case ok && !entry.invalidated && now.Sub(entry.fetchedAt) < c.ttl:
+		c.metrics.Hits++
+		c.mu.Unlock()
+		return entry.intel
+	case ok && !entry.invalidated && now.Sub(entry.fetchedAt) < c.ttl+c.maxStale:
+		c.metrics.StaleHits++
+		if !entry.refreshing {
+			entry.refreshing = true
+			go c.load(city, key, generation)
+		}
+		c.mu.Unlock()
+		return entry.intel
+	}
+	c.metrics.Misses++
+	c.mu.Unlock()
+
+	select {
+	case result := <-c.loads.DoChan(loadKey(key, generation), func() (interface{}, error) {
+		return c.load(city, key, generation), nil
+	}):
+		return result.Val.(cityIntelligence)
+	case <-ctx.Done():
+		return cityIntelligence{}
+	}
+}
+
+// loadKey identifies the loads of a city sharing one MI8 call; a load started before an invalidation is not shared
+// with the ones started after.
+func loadKey(key string, generation uint64) string {
+	return fmt.Sprintf("%s#%d", key, generation)
+}
+
+// load asks MI8 for city and stores the answer. It outlives the request that started it, since others may wait
+// for it too.
+func (c *cityIntelligenceCache) load(city, key string, generation uint64) cityIntelligence {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*mi8RPCTimeout)
+	defer cancel()
+
+	intel, err := loadCityIntelligence(ctx, city)
+	return c.store(key, generation, intel, err)
+}
+
+// store caches what MI8 returned and returns what to serve. Parts MI8 failed to return are taken from the
+// previous entry, which is then left to expire so the next request tries MI8 again. Loads started before the
+// latest invalidation of the city are returned without being cached.
+func (c *cityIntelligenceCache) store(key string, generation uint64, intel cityIntelligence, err error) cityIntelligence {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generations[key] != generation {
+		return intel
+	}
+
+	previous, ok := c.entries[key]
+	if err == nil {
+		c.entries[key] = &cityCacheEntry{intel: intel, fetchedAt: time.Now()}
+		return intel
+	}
+
+	c.metrics.RefreshFailures++
+	if !ok {
+		return intel
+	}
+	c.metrics.Fallbacks++
+	previous.refreshing = false
+	if intel.Scores == nil {
+		intel.Scores = previous.intel.Scores
+	}
+	if intel.LatestNews == nil {
+		intel.LatestNews = previous.intel.LatestNews
+	}
+	previous.intel = intel
+	return intel
+}
+
+// invalidate makes the next request for city ask MI8, keeping the entry in case MI8 is unavailable then. Loads
+// of city already running are not cached.
+func (c *cityIntelligenceCache) invalidate(city string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := cityCacheKey(city)
+	c.generations[key]++
+	if entry, ok := c.entries[key]; ok {
+		entry.invalidated = true
+		c.metrics.Invalidations++
+	}
+}
+
+func (c *cityIntelligenceCache) snapshot() CityCacheMetrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	metrics := c.metrics
+	metrics.Entries = len(c.entries)
+	return metrics
+}
+
+// loadCityIntelligence asks MI8 for the scores and news of city. It returns what it got along with an error
+// when either call failed.
+func loadCityIntelligence(ctx context.Context, city string) (cityIntelligence, error) {
+	var intel cityIntelligence
+
+	score, scoreErr := getCityScoresFromMI8(ctx, city)
+	if scoreErr != nil {
+		log.Printf("mi8 city scores unavailable for city=%s: %v", city, scoreErr)
+	}
+	intel.Scores = score
+
+	news, newsErr := getNewsFromMI8(ctx, city)
+	if newsErr != nil {
+		log.Printf("mi8 news unavailable for city=%s: %v", city, newsErr)
+	} else {
+		titles := make([]NewsTitle, 0, len(news))
+		for _, n := range news {
+			titles = append(titles, NewsTitle{Title: n.Title})
+		}
+		intel.LatestNews = titles
+	}
+
+	if scoreErr != nil || newsErr != nil {
+		return intel, fmt.Errorf("mi8 unavailable for city %q", city)
+	}
+	return intel, nil
+}
+
+// getCityCacheMetrics handles GET /metrics/city-cache - Reports the city intelligence cache counters
+func getCityCacheMetrics(w http.ResponseWriter, r *http.Request) error {
+	return NewResponseWriter(w).JSON(http.StatusOK, cityCache.snapshot())
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/thomasrubini/polymove/common"
+	"github.com/thomasrubini/polymove/common/apierror"
+	"github.com/thomasrubini/polymove/common/proto"
+)
+
+const (
+	// erasmumuCallTimeout bounds each attempt of an Erasmumu call, retries get a fresh one.
+	erasmumuCallTimeout = 3 * time.Second
+	// erasmumuMaxAttempts bounds how often an idempotent call is tried.
+	erasmumuMaxAttempts = 3
+	// erasmumuRetryBackoff is the wait before the first retry; it doubles for each further one, with jitter.
+	erasmumuRetryBackoff = 100 * time.Millisecond
+
+	// erasmumuBreakerThreshold is how many consecutive failed attempts open the circuit breaker.
+	erasmumuBreakerThreshold = 5
+	// erasmumuBreakerCooldown is how long an open breaker fails fast before letting one trial call through.
+	erasmumuBreakerCooldown = 30 * time.Second
+
+	// erasmumuBatchSize is the most offers Erasmumu's GetOffers returns at once.
+	erasmumuBatchSize = 100
+)
+
+// errErasmumuUnavailable marks failures where Erasmumu could not answer at all.
+var errErasmumuUnavailable = apierror.UpstreamUnavailable("erasmumu_unavailable", "erasmumu unavailable", nil)
+
+var (
+	erasmumu     *ErasmumuClient
+	erasmumuOnce sync.Once
+)
+
+// ErasmumuClient calls Erasmumu: offers over gRPC, seat reservations over HTTP. Every attempt has its own
+// deadline, idempotent calls are retried with backoff when Erasmumu is unavailable, and a circuit breaker
+// fails calls fast while Erasmumu is down.
+type ErasmumuClient struct {
+	rpc     proto.ErasmumuServiceClient
+	baseURL string
+	http    *http.Client
+
+	CallTimeout  time.Duration
+	MaxAttempts  int
+	RetryBackoff time.Duration
+
+	// Token returns the bearer token of the HTTP calls; Erasmumu only lets services and admins reserve seats.
+	Token func() (string, error)
+
+	breaker *circuitBreaker
+	metrics *erasmumuMetrics
+}
+
+// NewErasmumuClient returns a client calling the gRPC service rpc and the HTTP API at baseURL with httpClient.
+func NewErasmumuClient(rpc proto.ErasmumuServiceClient, baseURL string, httpClient *http.Client) *ErasmumuClient {
+	return &ErasmumuClient{
+		rpc:          rpc,
+		baseURL:      baseURL,
+		http:         httpClient,
+		CallTimeout:  erasmumuCallTimeout,
+		MaxAttempts:  erasmumuMaxAttempts,
+		RetryBackoff: erasmumuRetryBackoff,
+		breaker:      newCircuitBreaker(erasmumuBreakerThreshold, erasmumuBreakerCooldown),
+		metrics:      &erasmumuMetrics{calls: make(map[string]*ErasmumuCallMetrics)},
+	}
+}
+
+// getErasmumuClient returns the client configured by ERASMUMU_GRPC_HOST, ERASMUMU_GRPC_PORT and ERASMUMU_URL.
+func getErasmumuClient() *ErasmumuClient {
+	erasmumuOnce.Do(func() {
+		host := getEnv("ERASMUMU_GRPC_HOST", "localhost")
+		port := getEnv("ERASMUMU_GRPC_PORT", "9091")
+
+		addr := net.JoinHostPort(host, port)
+		conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			log.Fatalf("Failed to connect to Erasmumu gRPC server: %v", err)
+		}
+		erasmumu = NewErasmumuClient(proto.NewErasmumuServiceClient(conn), getEnv("ERASMUMU_URL", "http://erasmumu:8081"), &http.Client{})
+		erasmumu.Token = serviceToken
+	})
+	return erasmumu
+}
+
+// call runs fn under the breaker with a deadline per attempt. Only idempotent calls are retried, and only when
+// Erasmumu was unavailable; errors Erasmumu answered with are returned as they are.
+func (c *ErasmumuClient) call(ctx context.Context, name string, idempotent bool, fn func(ctx context.Context) error) error {
+	attempts := 1
+	if idempotent && c.MaxAttempts > 1 {
+		attempts = c.MaxAttempts
+	}
+
+	start := time.Now()
+	var err error
+	for attempt := 1; ; attempt++ {
+		if !c.breaker.allow() {
+			c.metrics.shortCircuited(name)
+			return fmt.Errorf("%w: %s: circuit breaker open", errErasmumuUnavailable, name)
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, c.CallTimeout)
+		err = fn(attemptCtx)
+		cancel()
+
+		if !errors.Is(err, errErasmumuUnavailable) {
+			c.breaker.success()
+			break
+		}
+		if ctx.Err() != nil {
+			// The caller gave up; that says nothing about Erasmumu.
+			c.breaker.abandon()
+			break
+		}
+		c.breaker.failure()
+		if attempt >= attempts {
+			break
+		}
+
+		c.metrics.retried(name)
+		if !sleepContext(ctx, c.backoff(attempt)) {
+			break
+		}
+	}
+
+	c.metrics.observe(name, err, time.Since(start))
+	return err
+}
+
+// backoff is the wait after a failed attempt: RetryBackoff doubled per attempt, plus up to 50% jitter.
+func (c *ErasmumuClient) backoff(attempt int) time.Duration {
+	wait := c.RetryBackoff << (attempt - 1)
+	return wait + time.Duration(rand.Int63n(int64(wait)/2+1))
+}
+
+// sleepContext waits for d; it returns false if ctx is done first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// GetOffer loads one offer; ok is false when Erasmumu does not know it.
+func (c *ErasmumuClient) GetOffer(ctx context.Context, id int) (offer common.Offer, ok bool, err error) {
+	err = c.call(ctx, "GetOffer", true, func(ctx context.Context) error {
+		resp, err := c.rpc.GetOffer(ctx, &proto.GetOfferRequest{Id: int32(id)})
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if err != nil {
+			return erasmumuError("GetOffer", err)
+		}
+		offer, ok = fromProtoOffer(resp), true
+		return nil
+	})
+	return offer, ok, err
+}
+
+// GetOffers loads offers by id in batches, keyed by id; ids Erasmumu does not know are left out.
+func (c *ErasmumuClient) GetOffers(ctx context.Context, ids []int) (map[int]common.Offer, error) {
+	offers := make(map[int]common.Offer, len(ids))
+	seen := make(map[int]bool, len(ids))
+	unique := make([]int32, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, int32(id))
+		}
+	}
+
+	for start := 0; start < len(unique); start += erasmumuBatchSize {
+		batch := unique[start:min(start+erasmumuBatchSize, len(unique))]
+
+		err := c.call(ctx, "GetOffers", true, func(ctx context.Context) error {
+			resp, err := c.rpc.GetOffers(ctx, &proto.GetOffersRequest{Ids: batch})
+			if err != nil {
+				return erasmumuError("GetOffers", err)
+			}
+			for _, o := range resp.Offers {
+				offers[int(o.Id)] = fromProtoOffer(o)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return offers, nil
+}
+
+// ListOffers loads one page of offers matching req.
+func (c *ErasmumuClient) ListOffers(ctx context.Context, req *proto.ListOffersRequest) (common.OfferPage, error) {
+	var page common.OfferPage
+	err := c.call(ctx, "ListOffers", true, func(ctx context.Context) error {
+		resp, err := c.rpc.ListOffers(ctx, req)
+		if err != nil {
+			return erasmumuError("ListOffers", err)
+		}
+
+		page = common.OfferPage{Offers: make([]common.Offer, 0, len(resp.Offers)), NextCursor: resp.NextCursor}
+		for _, o := range resp.Offers {
+			page.Offers = append(page.Offers, fromProtoOffer(o))
+		}
+		return nil
+	})
+	return page, err
+}
+
+// Breaker states.
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half_open"
+)
+
+// circuitBreaker opens after threshold consecutive failures and then rejects calls for cooldown. After that it
+// is half-open: one trial call goes through, closing the breaker on success and reopening it on failure.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+
+	state        string
+	failures     int
+	openedAt     time.Time
+	probing      bool
+	probeStarted time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, state: breakerClosed}
+}
+
+// allow reports whether a call may go through now.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	switch b.state {
+	case breakerOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+	case breakerHalfOpen:
+		// A trial that never reported back no longer blocks the next one after a cooldown.
+		if b.probing && now.Sub(b.probeStarted) < b.cooldown {
+			return false
+		}
+	default:
+		return true
+	}
+	b.probing, b.probeStarted = true, now
+	return true
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != breakerClosed {
+		log.Printf("Erasmumu circuit breaker closed")
+	}
+	b.state, b.failures, b.probing = breakerClosed, 0, false
+}
+
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.state == breakerHalfOpen || (b.state == breakerClosed && b.failures >= b.threshold) {
+		log.Printf("Erasmumu circuit breaker opened after %d consecutive failures", b.failures)
+		b.state, b.openedAt = breakerOpen, time.Now()
+	}
+}
+
+// abandon frees the trial slot of a call whose outcome is unknown.
+func (b *circuitBreaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+func (b *circuitBreaker) snapshot() (string, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state, b.failures
+}
+
+// ErasmumuCallMetrics count the outcomes of one kind of Erasmumu call. Failures are calls that ended with
+// Erasmumu unavailable, after their retries; ShortCircuited calls were rejected by the open breaker.
+type ErasmumuCallMetrics struct {
+	Calls            int64   `json:"calls"`
+	Failures         int64   `json:"failures"`
+	Retries          int64   `json:"retries"`
+	ShortCircuited   int64   `json:"short_circuited"`
+	AverageLatencyMs float64 `json:"average_latency_ms"`
+
+	totalLatency time.Duration
+}
+
+// ErasmumuMetrics is the body of GET /metrics/erasmumu.
+type ErasmumuMetrics struct {
+	Breaker             string                         `json:"breaker"`
+	ConsecutiveFailures int                            `json:"consecutive_failures"`
+	Calls               map[string]ErasmumuCallMetrics `json:"calls"`
+}
+
+type erasmumuMetrics struct {
+	mu    sync.Mutex
+	calls map[string]*ErasmumuCallMetrics
+}
+
+func (m *erasmumuMetrics) get(name string) *ErasmumuCallMetrics {
+	metrics, ok := m.calls[name]
+	if !ok {
+		metrics = &ErasmumuCallMetrics{}
+		m.calls[name] = metrics
+	}
+	return metrics
+}
+
+func (m *erasmumuMetrics) observe(name string, err error, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	metrics := m.get(name)
+	metrics.Calls++
+	metrics.totalLatency += latency
+	if errors.Is(err, errErasmumuUnavailable) {
+		metrics.Failures++
+	}
+}
+
+func (m *erasmumuMetrics) retried(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.get(name).Retries++
+}
+
+func (m *erasmumuMetrics) shortCircuited(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	metrics := m.get(name)
+	metrics.Calls++
+	metrics.Failures++
+	metrics.ShortCircuited++
+}
+
+// Metrics returns a snapshot of the breaker state and the per-call counters.
+func (c *ErasmumuClient) Metrics() ErasmumuMetrics {
+	state, failures := c.breaker.snapshot()
+	snapshot := ErasmumuMetrics{Breaker: state, ConsecutiveFailures: failures, Calls: make(map[string]ErasmumuCallMetrics)}
+
+	c.metrics.mu.Lock()
+	defer c.metrics.mu.Unlock()
+
+	for name, counters := range c.metrics.calls {
+		metrics := *counters
+		if timed := metrics.Calls - metrics.ShortCircuited; timed > 0 {
+			metrics.AverageLatencyMs = float64(metrics.totalLatency.Microseconds()) / 1000 / float64(timed)
+		}
+		snapshot.Calls[name] = metrics
+	}
+	return snapshot
+}
+
+// getErasmumuMetrics handles GET /metrics/erasmumu - Reports the Erasmumu client's breaker state and call counters
+func getErasmumuMetrics(w http.ResponseWriter, r *http.Request) error {
+	return NewResponseWriter(w).JSON(http.StatusOK, getErasmumuClient().Metrics())
+}
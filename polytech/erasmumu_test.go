@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thomasrubini/polymove/common"
+	"github.com/thomasrubini/polymove/common/apierror"
+)
+
+// fakeErasmumu is an Erasmumu reservation API answering each request with the next queued status, then with
+// the last one.
+type fakeErasmumu struct {
+	mu       sync.Mutex
+	statuses []int
+	requests int
+	tokens   []string
+}
+
+func (f *fakeErasmumu) answer(statuses ...int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statuses = statuses
+}
+
+func (f *fakeErasmumu) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests
+}
+
+func (f *fakeErasmumu) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests++
+	f.tokens = append(f.tokens, r.Header.Get("Authorization"))
+	status := f.statuses[0]
+	if len(f.statuses) > 1 {
+		f.statuses = f.statuses[1:]
+	}
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if status >= http.StatusBadRequest {
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Message: http.StatusText(status)})
+		return
+	}
+	_ = json.NewEncoder(w).Encode(common.Reservation{ID: 7, OfferID: 3, Holder: reservationHolder(1), Status: "held"})
+}
+
+// newTestErasmumuClient returns a client of a fake Erasmumu answering statuses, with short waits and cooldown.
+func newTestErasmumuClient(t *testing.T, cooldown time.Duration, statuses ...int) (*ErasmumuClient, *fakeErasmumu) {
+	t.Helper()
+
+	fake := &fakeErasmumu{}
+	fake.answer(statuses...)
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client := NewErasmumuClient(nil, server.URL, server.Client())
+	client.RetryBackoff = time.Millisecond
+	client.Token = func() (string, error) { return "service-token", nil }
+	client.breaker = newCircuitBreaker(erasmumuBreakerThreshold, cooldown)
+	return client, fake
+}
+
+func TestIdempotentCallsAreRetried(t *testing.T) {
+	client, fake := newTestErasmumuClient(t, time.Minute, http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK)
+
+	reservation, err := client.ConfirmSeat(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("ConfirmSeat: %v", err)
+	}
+	if reservation.ID != 7 {
+		t.Fatalf("reservation id = %d, want 7", reservation.ID)
+	}
+	if got := fake.count(); got != erasmumuMaxAttempts {
+		t.Fatalf("requests = %d, want %d", got, erasmumuMaxAttempts)
+	}
+	for _, token := range fake.tokens {
+		if token != "Bearer service-token" {
+			t.Fatalf("Authorization = %q, want the service token", token)
+		}
+	}
+
+	metrics := client.Metrics()
+	if got := metrics.Calls["ConfirmSeat"]; got.Calls != 1 || got.Retries != 2 || got.Failures != 0 {
+		t.Fatalf("ConfirmSeat metrics = %+v, want 1 call, 2 retries, no failure", got)
+	}
+	if metrics.Breaker != breakerClosed || metrics.ConsecutiveFailures != 0 {
+		t.Fatalf("breaker = %s with %d failures, want closed with none", metrics.Breaker, metrics.ConsecutiveFailures)
+	}
+}
+
+func TestIdempotentCallsGiveUpAfterMaxAttempts(t *testing.T) {
+	client, fake := newTestErasmumuClient(t, time.Minute, http.StatusServiceUnavailable)
+
+	_, err := client.ReleaseSeat(context.Background(), 7)
+	if !errors.Is(err, errErasmumuUnavailable) {
+		t.Fatalf("ReleaseSeat error = %v, want erasmumu unavailable", err)
+	}
+	if got := fake.count(); got != erasmumuMaxAttempts {
+		t.Fatalf("requests = %d, want %d", got, erasmumuMaxAttempts)
+	}
+
+	if got := client.Metrics().Calls["ReleaseSeat"]; got.Calls != 1 || got.Retries != 2 || got.Failures != 1 {
+		t.Fatalf("ReleaseSeat metrics = %+v, want 1 call, 2 retries, 1 failure", got)
+	}
+}
+
+func TestReserveSeatIsNotRetried(t *testing.T) {
+	client, fake := newTestErasmumuClient(t, time.Minute, http.StatusServiceUnavailable, http.StatusCreated)
+
+	_, err := client.ReserveSeat(context.Background(), 3, 1)
+	if !errors.Is(err, errErasmumuUnavailable) {
+		t.Fatalf("ReserveSeat error = %v, want erasmumu unavailable", err)
+	}
+	if got := fake.count(); got != 1 {
+		t.Fatalf("requests = %d, want a single one", got)
+	}
+
+	if got := client.Metrics().Calls["ReserveSeat"]; got.Calls != 1 || got.Retries != 0 || got.Failures != 1 {
+		t.Fatalf("ReserveSeat metrics = %+v, want 1 call, no retry, 1 failure", got)
+	}
+}
+
+func TestAnsweredErrorsAreNotRetried(t *testing.T) {
+	client, fake := newTestErasmumuClient(t, time.Minute, http.StatusConflict)
+
+	_, err := client.ConfirmSeat(context.Background(), 7)
+	if !errors.Is(err, apierror.ErrConflict) {
+		t.Fatalf("ConfirmSeat error = %v, want a conflict", err)
+	}
+	if got := fake.count(); got != 1 {
+		t.Fatalf("requests = %d, want a single one", got)
+	}
+
+	metrics := client.Metrics()
+	if got := metrics.Calls["ConfirmSeat"]; got.Calls != 1 || got.Retries != 0 || got.Failures != 0 {
+		t.Fatalf("ConfirmSeat metrics = %+v, want 1 call, no retry, no failure", got)
+	}
+	if metrics.Breaker != breakerClosed {
+		t.Fatalf("breaker = %s, want closed: Erasmumu answered", metrics.Breaker)
+	}
+}
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	client, fake := newTestErasmumuClient(t, time.Minute, http.StatusServiceUnavailable)
+
+	for i := 0; i < erasmumuBreakerThreshold; i++ {
+		if _, err := client.ReserveSeat(context.Background(), 3, 1); !errors.Is(err, errErasmumuUnavailable) {
+			t.Fatalf("ReserveSeat %d error = %v, want erasmumu unavailable", i, err)
+		}
+		if state := client.Metrics().Breaker; i < erasmumuBreakerThreshold-1 && state != breakerClosed {
+			t.Fatalf("breaker = %s after %d failures, want closed", state, i+1)
+		}
+	}
+
+	metrics := client.Metrics()
+	if metrics.Breaker != breakerOpen || metrics.ConsecutiveFailures != erasmumuBreakerThreshold {
+		t.Fatalf("breaker = %s with %d failures, want open with %d", metrics.Breaker, metrics.ConsecutiveFailures, erasmumuBreakerThreshold)
+	}
+
+	_, err := client.ConfirmSeat(context.Background(), 7)
+	if !errors.Is(err, errErasmumuUnavailable) {
+		t.Fatalf("ConfirmSeat error = %v, want erasmumu unavailable", err)
+	}
+	if got := fake.count(); got != erasmumuBreakerThreshold {
+		t.Fatalf("requests = %d, want %d: the open breaker must not reach Erasmumu", got, erasmumuBreakerThreshold)
+	}
+
+	if got := client.Metrics().Calls["ConfirmSeat"]; got.Calls != 1 || got.Failures != 1 || got.ShortCircuited != 1 || got.Retries != 0 {
+		t.Fatalf("ConfirmSeat metrics = %+v, want 1 short-circuited call", got)
+	}
+}
+
+func TestBreakerHalfOpenProbe(t *testing.T) {
+	const cooldown = 20 * time.Millisecond
+	client, fake := newTestErasmumuClient(t, cooldown, http.StatusServiceUnavailable)
+
+	for i := 0; i < erasmumuBreakerThreshold; i++ {
+		_, _ = client.ReserveSeat(context.Background(), 3, 1)
+	}
+	if state := client.Metrics().Breaker; state != breakerOpen {
+		t.Fatalf("breaker = %s, want open", state)
+	}
+
+	// A failed trial reopens the breaker for another cooldown.
+	time.Sleep(2 * cooldown)
+	if _, err := client.ReserveSeat(context.Background(), 3, 1); !errors.Is(err, errErasmumuUnavailable) {
+		t.Fatalf("trial error = %v, want erasmumu unavailable", err)
+	}
+	if got := fake.count(); got != erasmumuBreakerThreshold+1 {
+		t.Fatalf("requests = %d, want the trial to reach Erasmumu", got)
+	}
+	if state := client.Metrics().Breaker; state != breakerOpen {
+		t.Fatalf("breaker = %s after a failed trial, want open", state)
+	}
+	if _, err := client.ReserveSeat(context.Background(), 3, 1); !errors.Is(err, errErasmumuUnavailable) || fake.count() != erasmumuBreakerThreshold+1 {
+		t.Fatalf("call right after a failed trial reached Erasmumu (error %v)", err)
+	}
+
+	// A successful trial closes it.
+	fake.answer(http.StatusCreated)
+	time.Sleep(2 * cooldown)
+	if _, err := client.ReserveSeat(context.Background(), 3, 1); err != nil {
+		t.Fatalf("trial: %v", err)
+	}
+	metrics := client.Metrics()
+	if metrics.Breaker != breakerClosed || metrics.ConsecutiveFailures != 0 {
+		t.Fatalf("breaker = %s with %d failures after a successful trial, want closed with none", metrics.Breaker, metrics.ConsecutiveFailures)
+	}
+
+	if got := metrics.Calls["ReserveSeat"]; got.Calls != erasmumuBreakerThreshold+3 || got.Failures != erasmumuBreakerThreshold+2 || got.ShortCircuited != 1 {
+		t.Fatalf("ReserveSeat metrics = %+v, want %d calls, %d failures, 1 short-circuited", got, erasmumuBreakerThreshold+3, erasmumuBreakerThreshold+2)
+	}
+}
+
+func TestBreakerLetsOneTrialThrough(t *testing.T) {
+	b := newCircuitBreaker(1, time.Hour)
+	b.failure()
+	b.openedAt = time.Now().Add(-2 * time.Hour)
+
+	if !b.allow() {
+		t.Fatal("first call after the cooldown was rejected, want a trial")
+	}
+	if b.allow() {
+		t.Fatal("second call during the trial was allowed, want it rejected")
+	}
+	b.abandon()
+	if !b.allow() {
+		t.Fatal("call after an abandoned trial was rejected, want a new trial")
+	}
+}
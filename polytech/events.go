@@ -1,18 +1,31 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
+	"github.com/lib/pq"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/thomasrubini/polymove/common"
 )
 
+const (
+	notificationTypeNewOffer     = "new_offer"
+	notificationTypeOfferClosed  = "offer_closed"
+	notificationTypeOfferDeleted = "offer_deleted"
+	notificationTypeOfferUpdated = "offer_updated"
+)
+
 var rmqConn *amqp.Connection
 var rmqChannel *amqp.Channel
 
+// outbox holds student events until the relay has published them.
+var outbox *common.Outbox
+
 type StudentRegisteredEvent struct {
 	StudentID int    `json:"student_id"`
 	Name      string `json:"name"`
@@ -20,6 +33,11 @@ type StudentRegisteredEvent struct {
 	CreatedAt string `json:"created_at"`
 }
 
+// rabbitMQURL locates the broker the outbox relay publishes to; the relay connects and reconnects on its own.
+func rabbitMQURL() string {
+	return common.RabbitMQURL(getEnv("RABBITMQ_HOST", "localhost"), getEnv("RABBITMQ_PORT", "5672"))
+}
+
 // initRabbitMQ initializes a RabbitMQ connection and channel for Polytech publishers.
 func initRabbitMQ() {
 	rmqConn, rmqChannel = common.InitRabbitMQ(
@@ -28,119 +46,312 @@ func initRabbitMQ() {
 	)
 }
 
-// publishStudentRegisteredEvent emits the student.registered event for new students.
-func publishStudentRegisteredEvent(student Student) error {
-	event := StudentRegisteredEvent{
+// enqueueStudentRegisteredEvent records the student.registered event for a new student inside tx.
+func enqueueStudentRegisteredEvent(tx *sql.Tx, student Student) error {
+	return outbox.Enqueue(tx, common.RoutingKeyStudentRegistered, StudentRegisteredEvent{
 		StudentID: student.ID,
 		Name:      student.Name,
 		Domain:    student.Domain,
 		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// enqueueInternshipStatusChangedEvent records the internship.status_changed event of one transition inside tx.
+func enqueueInternshipStatusChangedEvent(tx *sql.Tx, internship Internship, change InternshipStatusChange) error {
+	return outbox.Enqueue(tx, common.RoutingKeyInternshipStatusChanged, common.InternshipStatusChangedEvent{
+		InternshipID: internship.ID,
+		StudentID:    internship.StudentID,
+		OfferID:      internship.OfferID,
+		From:         change.From,
+		To:           change.To,
+		Reason:       change.Reason,
+		ChangedAt:    change.ChangedAt,
+	})
+}
+
+// processOfferCreatedEvent creates one notification for each student matching the offer domain
+// and its mandatory requirements.
+func processOfferCreatedEvent(payload []byte) error {
+	var event common.OfferCreatedEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal event: %w", err)
 	}
 
-	body, err := json.Marshal(event)
-	if err != nil {
-		return fmt.Errorf("failed to marshal student event: %w", err)
-	}
-
-	err = rmqChannel.Publish(
-		common.TopicExchange,
-		common.RoutingKeyStudentRegistered,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType:  "application/json",
-			DeliveryMode: amqp.Persistent,
-			Body:         body,
-		},
-	)
+	log.Printf("Received offer from queue: offer_id=%d domain=%s city=%s", event.OfferID, event.Domain, event.City)
+
+	if event.OfferID <= 0 || event.Domain == "" {
+		return fmt.Errorf("invalid offer.created event")
+	}
+
+	eligible, err := eligibleStudents(db, common.Offer{ID: event.OfferID, Domain: event.Domain, Skills: event.Skills, Languages: event.Languages})
 	if err != nil {
-		return fmt.Errorf("failed to publish event: %w", err)
+		return err
+	}
+
+	message := newOfferMessage(event.Title, event.EmployerName, event.City, event.Domain)
+	for _, studentID := range eligible {
+		_, err := db.Exec(
+			"INSERT INTO notifications (student_id, type, offer_id, message, read) VALUES ($1, $2, $3, $4, false) ON CONFLICT (student_id, offer_id, type) DO NOTHING",
+			studentID,
+			notificationTypeNewOffer,
+			event.OfferID,
+			message,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert notification: %w", err)
+		}
 	}
 
 	return nil
 }
 
-// consumeOfferCreatedEvents subscribes to offer.created and stores notifications for matching students.
-func consumeOfferCreatedEvents(ch *amqp.Channel) {
-	queueName := common.QueuePolytechOfferCreated
-	routingKey := common.RoutingKeyOfferCreated
+// newOfferMessage renders the text of a new_offer notification; employer is empty for offers without one.
+func newOfferMessage(title, employer, city, domain string) string {
+	if employer != "" {
+		return fmt.Sprintf("New offer '%s' at %s in %s matches your domain %s.", title, employer, city, domain)
+	}
+	return fmt.Sprintf("New offer '%s' in %s matches your domain %s.", title, city, domain)
+}
+
+// processOfferUpdatedEvent keeps unread new_offer notifications in sync with the updated offer.
+func processOfferUpdatedEvent(payload []byte) error {
+	var event common.OfferUpdatedEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal event: %w", err)
+	}
+
+	if event.OfferID <= 0 || event.Domain == "" {
+		return fmt.Errorf("invalid offer.updated event")
+	}
 
-	queue, err := ch.QueueDeclare(queueName, true, false, false, false, nil)
+	// Internships keep the dates they were created with, which guard overlaps, but follow the offer location.
+	_, err := db.Exec("UPDATE internships SET city = $2, domain = $3 WHERE offer_id = $1", event.OfferID, event.City, event.Domain)
 	if err != nil {
-		log.Printf("Failed to declare queue: %v", err)
-		return
+		return fmt.Errorf("failed to update internship offers: %w", err)
+	}
+
+	if !event.Available {
+		message := fmt.Sprintf("Offer '%s' in %s is no longer available.", event.Title, event.City)
+		return retractOfferNotifications(event.OfferID, notificationTypeOfferClosed, message)
 	}
 
-	err = ch.QueueBind(queue.Name, routingKey, common.TopicExchange, false, nil)
+	tx, err := db.Begin()
 	if err != nil {
-		log.Printf("Failed to bind queue: %v", err)
-		return
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	deliveries, err := ch.Consume(queue.Name, "", false, false, false, false, nil)
+	eligible, err := eligibleStudents(tx, common.Offer{ID: event.OfferID, Domain: event.Domain, Skills: event.Skills, Languages: event.Languages})
 	if err != nil {
-		log.Printf("Failed to register consumer: %v", err)
-		return
+		return err
 	}
 
-	log.Printf("Subscribed to routing key %s", routingKey)
+	// Students whose domain or requirements no longer match lose the notification if they have not seen it yet.
+	_, err = tx.Exec(
+		"DELETE FROM notifications WHERE offer_id = $1 AND type = $2 AND read = false AND NOT (student_id = ANY($3))",
+		event.OfferID,
+		notificationTypeNewOffer,
+		pq.Array(eligible),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to retract notifications: %w", err)
+	}
 
-	for msg := range deliveries {
-		if err := processOfferCreatedEvent(msg.Body); err != nil {
-			log.Printf("Failed to process offer.created event: %v", err)
-			if nackErr := msg.Nack(false, true); nackErr != nil {
-				log.Printf("Failed to nack message: %v", nackErr)
-			}
+	message := newOfferMessage(event.Title, event.EmployerName, event.City, event.Domain)
+	_, err = tx.Exec(
+		"UPDATE notifications SET message = $1 WHERE offer_id = $2 AND type = $3 AND read = false",
+		message,
+		event.OfferID,
+		notificationTypeNewOffer,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to refresh notifications: %w", err)
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO notifications (student_id, type, offer_id, message, read) SELECT id, $1, $2, $3, false FROM UNNEST($4::int[]) AS id ON CONFLICT (student_id, offer_id, type) DO NOTHING",
+		notificationTypeNewOffer,
+		event.OfferID,
+		message,
+		pq.Array(eligible),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert notifications: %w", err)
+	}
+
+	// Students who already read about the offer are told what changed since.
+	if summary := describeOfferChanges(event.Changes); summary != "" {
+		_, err = tx.Exec(
+			"INSERT INTO notifications (student_id, type, offer_id, message, read) SELECT student_id, $1, offer_id, $2, false FROM notifications WHERE offer_id = $3 AND type = $4 AND read = true ON CONFLICT (student_id, offer_id, type) DO UPDATE SET message = EXCLUDED.message, read = false",
+			notificationTypeOfferUpdated,
+			fmt.Sprintf("Offer '%s' in %s was updated: %s.", event.Title, event.City, summary),
+			event.OfferID,
+			notificationTypeNewOffer,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert update notifications: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// eligibleStudents lists the ids of the students in the offer domain meeting its mandatory requirements, the ones
+// new_offer notifications go to.
+func eligibleStudents(q interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}, offer common.Offer) ([]int64, error) {
+	rows, err := q.Query("SELECT "+studentColumns+" FROM students WHERE domain = $1", offer.Domain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query matching students: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	eligible := []int64{}
+	for rows.Next() {
+		var student Student
+		if err := scanStudent(rows, &student); err != nil {
+			return nil, fmt.Errorf("failed to scan student: %w", err)
+		}
+		if len(missingRequirements(student, offer)) == 0 {
+			eligible = append(eligible, int64(student.ID))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed iterating students: %w", err)
+	}
+	return eligible, nil
+}
+
+// offerChangeLabels names the offer fields students care about in update notifications.
+var offerChangeLabels = map[string]string{
+	"title":     "title",
+	"city":      "city",
+	"domain":    "domain",
+	"salary":    "salary",
+	"currency":  "currency",
+	"payPeriod": "pay period",
+	"startDate": "start date",
+	"endDate":   "end date",
+	"seats":     "seats",
+}
+
+// describeOfferChanges renders a field-level diff as "salary increased from X to Y, ..." text.
+func describeOfferChanges(changes []common.FieldChange) string {
+	var parts []string
+	for _, change := range changes {
+		label, ok := offerChangeLabels[change.Field]
+		if !ok {
 			continue
 		}
 
-		if ackErr := msg.Ack(false); ackErr != nil {
-			log.Printf("Failed to ack message: %v", ackErr)
+		verb := "changed"
+		from, fromOK := change.From.(float64)
+		to, toOK := change.To.(float64)
+		if fromOK && toOK {
+			if to > from {
+				verb = "increased"
+			} else {
+				verb = "decreased"
+			}
 		}
+
+		parts = append(parts, fmt.Sprintf("%s %s from %v to %v", label, verb, change.From, change.To))
 	}
+	return strings.Join(parts, ", ")
 }
 
-// processOfferCreatedEvent creates one notification for each student matching the offer domain.
-func processOfferCreatedEvent(payload []byte) error {
-	var event common.OfferCreatedEvent
+// processOfferClosedEvent retracts notifications for an offer that stopped accepting students.
+func processOfferClosedEvent(payload []byte) error {
+	var event common.OfferClosedEvent
 	if err := json.Unmarshal(payload, &event); err != nil {
 		return fmt.Errorf("failed to unmarshal event: %w", err)
 	}
 
-	log.Printf("Received offer from queue: offer_id=%d domain=%s city=%s", event.OfferID, event.Domain, event.City)
+	if event.OfferID <= 0 {
+		return fmt.Errorf("invalid offer.closed event")
+	}
 
-	if event.OfferID <= 0 || event.Domain == "" {
-		return fmt.Errorf("invalid offer.created event")
+	message := fmt.Sprintf("Offer '%s' in %s has been closed.", event.Title, event.City)
+	return retractOfferNotifications(event.OfferID, notificationTypeOfferClosed, message)
+}
+
+// processOfferDeletedEvent retracts notifications for an offer that was removed.
+func processOfferDeletedEvent(payload []byte) error {
+	var event common.OfferDeletedEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal event: %w", err)
+	}
+
+	if event.OfferID <= 0 {
+		return fmt.Errorf("invalid offer.deleted event")
 	}
 
-	rows, err := db.Query("SELECT id FROM students WHERE domain = $1", event.Domain)
+	message := fmt.Sprintf("Offer '%s' in %s has been withdrawn.", event.Title, event.City)
+	return retractOfferNotifications(event.OfferID, notificationTypeOfferDeleted, message)
+}
+
+// retractOfferNotifications drops unread new_offer notifications for an offer and
+// tells students who already read them that the offer is gone.
+func retractOfferNotifications(offerID int, notificationType, message string) error {
+	tx, err := db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to query matching students: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	defer func() { _ = rows.Close() }()
+	defer func() { _ = tx.Rollback() }()
 
-	message := fmt.Sprintf("New offer '%s' in %s matches your domain %s.", event.Title, event.City, event.Domain)
-	for rows.Next() {
-		var studentID int
-		if err := rows.Scan(&studentID); err != nil {
-			return fmt.Errorf("failed to scan student: %w", err)
-		}
+	_, err = tx.Exec(
+		"DELETE FROM notifications WHERE offer_id = $1 AND type = $2 AND read = false",
+		offerID,
+		notificationTypeNewOffer,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to retract notifications: %w", err)
+	}
 
-		_, err := db.Exec(
-			"INSERT INTO notifications (student_id, type, offer_id, message, read) VALUES ($1, $2, $3, $4, false) ON CONFLICT (student_id, offer_id, type) DO NOTHING",
-			studentID,
-			"new_offer",
-			event.OfferID,
-			message,
-		)
-		if err != nil {
-			return fmt.Errorf("failed to insert notification: %w", err)
-		}
+	_, err = tx.Exec(
+		"INSERT INTO notifications (student_id, type, offer_id, message, read) SELECT student_id, $1, offer_id, $2, false FROM notifications WHERE offer_id = $3 AND type = $4 ON CONFLICT (student_id, offer_id, type) DO NOTHING",
+		notificationType,
+		message,
+		offerID,
+		notificationTypeNewOffer,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert notifications: %w", err)
 	}
 
-	if err := rows.Err(); err != nil {
-		return fmt.Errorf("failed iterating students: %w", err)
+	return tx.Commit()
+}
+
+// processOfferExpiredEvent marks unread notifications about an expired offer as obsolete.
+func processOfferExpiredEvent(payload []byte) error {
+	var event common.OfferExpiredEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal event: %w", err)
+	}
+
+	if event.OfferID <= 0 {
+		return fmt.Errorf("invalid offer.expired event")
+	}
+
+	_, err := db.Exec("UPDATE notifications SET obsolete = true WHERE offer_id = $1 AND read = false", event.OfferID)
+	if err != nil {
+		return fmt.Errorf("failed to mark notifications as obsolete: %w", err)
+	}
+
+	return nil
+}
+
+// processCityScoreChangedEvent drops the cached intelligence of a city whose scores MI8 changed.
+func processCityScoreChangedEvent(payload []byte) error {
+	var event common.CityScoreChangedEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal city_score_changed event: %w", err)
+	}
+	if event.City == "" {
+		return fmt.Errorf("invalid city_score_changed event")
 	}
 
+	cityCache.invalidate(event.City)
 	return nil
 }
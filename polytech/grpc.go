@@ -9,9 +9,12 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 
 	"github.com/thomasrubini/polymove/common"
+	"github.com/thomasrubini/polymove/common/apierror"
 	"github.com/thomasrubini/polymove/common/proto"
 )
 
@@ -84,3 +87,54 @@ func getNewsFromMI8(ctx context.Context, city string) ([]common.News, error) {
 	}
 	return news, nil
 }
+
+// erasmumuError tells Erasmumu outages apart from requests it rejected.
+func erasmumuError(call string, err error) error {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown:
+		return fmt.Errorf("%w: %s: %v", errErasmumuUnavailable, call, err)
+	case codes.InvalidArgument:
+		return apierror.Validation("invalid_query", "erasmumu %s rejected the request: %s", call, status.Convert(err).Message())
+	default:
+		return fmt.Errorf("erasmumu %s failed: %s", call, status.Convert(err).Message())
+	}
+}
+
+func fromProtoOffer(o *proto.Offer) common.Offer {
+	offer := common.Offer{
+		ID:               int(o.Id),
+		Title:            o.Title,
+		Link:             o.Link,
+		City:             o.City,
+		Domain:           o.Domain,
+		Salary:           int(o.Salary),
+		StartDate:        o.StartDate,
+		EndDate:          o.EndDate,
+		Available:        o.Available,
+		Seats:            int(o.Seats),
+		SeatsLeft:        int(o.SeatsLeft),
+		Country:          o.Country,
+		Latitude:         o.Latitude,
+		Longitude:        o.Longitude,
+		DistanceKm:       o.DistanceKm,
+		Currency:         o.Currency,
+		PayPeriod:        o.PayPeriod,
+		SalaryEURMonthly: int(o.SalaryEurMonthly),
+		EmployerName:     o.EmployerName,
+		Skills:           fromProtoRequirements(o.Skills),
+		Languages:        fromProtoRequirements(o.Languages),
+	}
+	if o.EmployerId != nil {
+		employerID := int(*o.EmployerId)
+		offer.EmployerID = &employerID
+	}
+	return offer
+}
+
+func fromProtoRequirements(requirements []*proto.Requirement) []common.Requirement {
+	out := make([]common.Requirement, 0, len(requirements))
+	for _, requirement := range requirements {
+		out = append(out, common.Requirement{Name: requirement.Name, Mandatory: requirement.Mandatory})
+	}
+	return out
+}
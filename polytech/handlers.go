@@ -8,22 +8,31 @@ import (
 	"log"
 	"net/http"
 	"net/url"
-	"sort"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/lib/pq"
 
 	"github.com/thomasrubini/polymove/common"
+	"github.com/thomasrubini/polymove/common/apierror"
+	"github.com/thomasrubini/polymove/common/proto"
 )
 
 // Internship represents a student's internship placement
 type Internship struct {
-	ID        int               `json:"id"`
-	StudentID int               `json:"student_id"`
-	OfferID   int               `json:"offer_id"`
-	Offer     *common.Offer     `json:"offer,omitempty"`
-	CityScore *common.CityScore `json:"city_score,omitempty"`
+	ID            int               `json:"id"`
+	StudentID     int               `json:"student_id"`
+	OfferID       int               `json:"offer_id"`
+	ReservationID int               `json:"reservation_id,omitempty"`
+	Offer         *common.Offer     `json:"offer,omitempty"`
+	CityScore     *common.CityScore `json:"city_score,omitempty"`
+	// Status follows internshipTransitions; History is only filled on single-internship responses.
+	Status          string                   `json:"status"`
+	StatusChangedAt string                   `json:"status_changed_at,omitempty"`
+	CreatedAt       string                   `json:"created_at,omitempty"`
+	History         []InternshipStatusChange `json:"history,omitempty"`
 }
 
 // InternshipRequest is the payload for creating an internship
@@ -40,39 +49,82 @@ type Notification struct {
 	OfferID   int    `json:"offer_id"`
 	Message   string `json:"message"`
 	Read      bool   `json:"read"`
+	Obsolete  bool   `json:"obsolete"`
 	CreatedAt string `json:"created_at"`
 }
 
+// studentIDFromRequest parses the {id} route variable of student routes.
+func studentIDFromRequest(r *http.Request) (int, error) {
+	raw := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(raw)
+	if err != nil || id <= 0 {
+		return 0, apierror.Validation("invalid_id", "invalid student id %q", raw)
+	}
+	return id, nil
+}
+
+func studentNotFound(id int) error {
+	return apierror.NotFound("student_not_found", "student with id %d not found", id)
+}
+
+// checkStudentExists returns studentNotFound when no student has id.
+func checkStudentExists(id int) error {
+	var exists bool
+	if err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM students WHERE id = $1)", id).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to get student: %w", err)
+	}
+	if !exists {
+		return studentNotFound(id)
+	}
+	return nil
+}
+
 // createStudent handles POST /student - Creates a new student
 func createStudent(w http.ResponseWriter, r *http.Request) error {
 	var student Student
 	if err := json.NewDecoder(r.Body).Decode(&student); err != nil {
-		return fmt.Errorf("failed to decode request body: %w", err)
+		return apierror.Validation("invalid_body", "failed to decode request body: %v", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer func() { _ = tx.Rollback() }()
+
+	student.Skills = common.NormalizeSkills(student.Skills)
+	student.Languages = common.NormalizeSkills(student.Languages)
 
-	query := "INSERT INTO students (name, domain) VALUES ($1, $2) RETURNING id"
-	if err := db.QueryRow(query, student.Name, student.Domain).Scan(&student.ID); err != nil {
+	query := "INSERT INTO students (name, domain, skills, languages) VALUES ($1, $2, $3, $4) RETURNING id"
+	if err := tx.QueryRow(query, student.Name, student.Domain, pq.Array(student.Skills), pq.Array(student.Languages)).Scan(&student.ID); err != nil {
 		return fmt.Errorf("failed to insert student: %w", err)
 	}
 
-	if err := publishStudentRegisteredEvent(student); err != nil {
-		return fmt.Errorf("failed to publish student.registered event: %w", err)
+	if err := enqueueStudentRegisteredEvent(tx, student); err != nil {
+		return err
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit student: %w", err)
+	}
+	outbox.Notify()
+
 	return NewResponseWriter(w).JSON(http.StatusCreated, student)
 }
 
 // getStudent handles GET /student/{id} - Retrieves a student by ID
 func getStudent(w http.ResponseWriter, r *http.Request) error {
-	vars := mux.Vars(r)
-	id := vars["id"]
+	id, err := studentIDFromRequest(r)
+	if err != nil {
+		return err
+	}
 
 	var student Student
-	query := "SELECT id, name, domain FROM students WHERE id = $1"
-	err := db.QueryRow(query, id).Scan(&student.ID, &student.Name, &student.Domain)
+	query := "SELECT " + studentColumns + " FROM students WHERE id = $1"
+	err = scanStudent(db.QueryRow(query, id), &student)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("student with id %s not found", id)
+			return studentNotFound(id)
 		}
 		return fmt.Errorf("failed to get student: %w", err)
 	}
@@ -88,10 +140,10 @@ func getStudentsByDomain(w http.ResponseWriter, r *http.Request) error {
 	var args []interface{}
 
 	if domain != "" {
-		query = "SELECT id, name, domain FROM students WHERE domain = $1"
+		query = "SELECT " + studentColumns + " FROM students WHERE domain = $1"
 		args = append(args, domain)
 	} else {
-		query = "SELECT id, name, domain FROM students"
+		query = "SELECT " + studentColumns + " FROM students"
 	}
 
 	rows, err := db.Query(query, args...)
@@ -103,7 +155,7 @@ func getStudentsByDomain(w http.ResponseWriter, r *http.Request) error {
 	var students []Student
 	for rows.Next() {
 		var student Student
-		if err := rows.Scan(&student.ID, &student.Name, &student.Domain); err != nil {
+		if err := scanStudent(rows, &student); err != nil {
 			return fmt.Errorf("failed to scan student: %w", err)
 		}
 		students = append(students, student)
@@ -114,33 +166,40 @@ func getStudentsByDomain(w http.ResponseWriter, r *http.Request) error {
 
 // updateStudent handles PUT /student/{id} - Updates an existing student
 func updateStudent(w http.ResponseWriter, r *http.Request) error {
-	vars := mux.Vars(r)
-	id := vars["id"]
+	id, err := studentIDFromRequest(r)
+	if err != nil {
+		return err
+	}
 
 	var student Student
 	if err := json.NewDecoder(r.Body).Decode(&student); err != nil {
-		return fmt.Errorf("failed to decode request body: %w", err)
+		return apierror.Validation("invalid_body", "failed to decode request body: %v", err)
 	}
 
-	query := "UPDATE students SET name = $1, domain = $2 WHERE id = $3"
-	result, err := db.Exec(query, student.Name, student.Domain, id)
+	student.Skills = common.NormalizeSkills(student.Skills)
+	student.Languages = common.NormalizeSkills(student.Languages)
+
+	query := "UPDATE students SET name = $1, domain = $2, skills = $3, languages = $4 WHERE id = $5"
+	result, err := db.Exec(query, student.Name, student.Domain, pq.Array(student.Skills), pq.Array(student.Languages), id)
 	if err != nil {
 		return fmt.Errorf("failed to update student: %w", err)
 	}
 
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
-		return fmt.Errorf("student with id %s not found", id)
+		return studentNotFound(id)
 	}
 
-	student.ID, _ = fmt.Sscanf(id, "%d", &student.ID)
+	student.ID = id
 	return NewResponseWriter(w).JSON(http.StatusOK, student)
 }
 
 // deleteStudent handles DELETE /student/{id} - Deletes a student
 func deleteStudent(w http.ResponseWriter, r *http.Request) error {
-	vars := mux.Vars(r)
-	id := vars["id"]
+	id, err := studentIDFromRequest(r)
+	if err != nil {
+		return err
+	}
 
 	query := "DELETE FROM students WHERE id = $1"
 	result, err := db.Exec(query, id)
@@ -150,7 +209,7 @@ func deleteStudent(w http.ResponseWriter, r *http.Request) error {
 
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
-		return fmt.Errorf("student with id %s not found", id)
+		return studentNotFound(id)
 	}
 
 	NewResponseWriter(w).NoContent()
@@ -158,59 +217,115 @@ func deleteStudent(w http.ResponseWriter, r *http.Request) error {
 }
 
 // createInternship handles POST /internship - Creates an internship for a student
-// Validates student exists, checks domain match with offer, fetches offer from Erasmumu
-// and city scores from MI8
+// Validates student exists, checks domain and mandatory requirements match with offer and that the student has
+// no active internship for the same offer or period, fetches offer from Erasmumu,
+// takes one of its seats through the reservation API and fetches city scores from MI8
 func createInternship(w http.ResponseWriter, r *http.Request) error {
 	var req InternshipRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		return fmt.Errorf("failed to decode request body: %w", err)
+		return apierror.Validation("invalid_body", "failed to decode request body: %v", err)
+	}
+	if err := authorizeStudent(r.Context(), req.StudentID); err != nil {
+		return err
 	}
 
 	// Validate student exists
 	var student Student
-	query := "SELECT id, name, domain FROM students WHERE id = $1"
-	err := db.QueryRow(query, req.StudentID).Scan(&student.ID, &student.Name, &student.Domain)
+	query := "SELECT " + studentColumns + " FROM students WHERE id = $1"
+	err := scanStudent(db.QueryRow(query, req.StudentID), &student)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("student with id %d not found", req.StudentID)
+			return studentNotFound(req.StudentID)
 		}
 		return fmt.Errorf("failed to get student: %w", err)
 	}
 
 	// Fetch offer from Erasmumu
-	erasmumuURL := getEnv("ERASMUMU_URL", "http://erasmumu:8081")
-	resp, err := http.Get(fmt.Sprintf("%s/offers/%d", erasmumuURL, req.OfferID))
+	offer, found, err := getErasmumuClient().GetOffer(r.Context(), req.OfferID)
 	if err != nil {
-		return fmt.Errorf("failed to fetch offer from erasmumu: %w", err)
+		return err
+	}
+	if !found {
+		return apierror.NotFound("offer_not_found", "offer with id %d not found", req.OfferID)
 	}
-	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode == http.StatusNotFound {
-		return fmt.Errorf("offer with id %d not found", req.OfferID)
+	// Check domain match between student and offer
+	if offer.Domain != student.Domain {
+		return apierror.Conflict("domain_mismatch", "student domain '%s' does not match offer domain '%s'", student.Domain, offer.Domain)
 	}
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("erasmumu returned status %d", resp.StatusCode)
+
+	// Check the student has every mandatory skill and language of the offer
+	if missing := missingRequirements(student, offer); len(missing) > 0 {
+		return &MissingRequirementsError{StudentID: student.ID, OfferID: offer.ID, Missing: missing}
 	}
 
-	var offer common.Offer
-	if err := json.NewDecoder(resp.Body).Decode(&offer); err != nil {
-		return fmt.Errorf("failed to decode offer response: %w", err)
+	// Check the student has no active internship for this offer or period
+	if conflict, err := findConflictingInternship(student.ID, offer.ID, offer.StartDate, offer.EndDate); err != nil {
+		return err
+	} else if conflict != nil {
+		return conflict
 	}
 
-	// Check domain match between student and offer
-	if offer.Domain != student.Domain {
-		return fmt.Errorf("student domain '%s' does not match offer domain '%s'", student.Domain, offer.Domain)
+	// Hold a seat in Erasmumu; it is given back unless the internship is stored
+	reservation, err := getErasmumuClient().ReserveSeat(r.Context(), req.OfferID, req.StudentID)
+	if err != nil {
+		return err
+	}
+	stored := false
+	defer func() {
+		if stored {
+			return
+		}
+		if _, err := getErasmumuClient().ReleaseSeat(context.Background(), reservation.ID); err != nil {
+			log.Printf("Failed to release reservation id=%d: %v", reservation.ID, err)
+		}
+	}()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer func() { _ = tx.Rollback() }()
 
 	// Insert internship into database
 	var internship Internship
-	query = "INSERT INTO internships (student_id, offer_id) VALUES ($1, $2) RETURNING id"
-	if err := db.QueryRow(query, req.StudentID, req.OfferID).Scan(&internship.ID); err != nil {
+	query = "INSERT INTO internships (student_id, offer_id, reservation_id, status, start_date, end_date, city, domain) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id"
+	err = tx.QueryRow(query, req.StudentID, req.OfferID, reservation.ID, internshipStatusApplied, offer.StartDate, offer.EndDate, offer.City, offer.Domain).Scan(&internship.ID)
+	if internshipGuardViolation(err) {
+		// A concurrent request won the race; name the internship it created.
+		_ = tx.Rollback()
+		conflict, lookupErr := findConflictingInternship(student.ID, offer.ID, offer.StartDate, offer.EndDate)
+		if lookupErr != nil {
+			return lookupErr
+		}
+		if conflict != nil {
+			return conflict
+		}
+		return &InternshipConflictError{StudentID: student.ID, Reason: "for the same offer or period"}
+	}
+	if err != nil {
 		return fmt.Errorf("failed to insert internship: %w", err)
 	}
 
+	applied, err := recordInternshipStatusChange(tx, internship.ID, "", internshipStatusApplied, "")
+	if err != nil {
+		return err
+	}
+
+	if _, err := getErasmumuClient().ConfirmSeat(r.Context(), reservation.ID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit internship: %w", err)
+	}
+	stored = true
+
 	internship.StudentID = req.StudentID
 	internship.OfferID = req.OfferID
+	internship.ReservationID = reservation.ID
+	internship.Status = applied.To
+	internship.StatusChangedAt = applied.ChangedAt
 	internship.Offer = &offer
 
 	// Fetch city scores from MI8 via gRPC
@@ -225,8 +340,18 @@ func createInternship(w http.ResponseWriter, r *http.Request) error {
 // OfferWithScore represents an offer with its associated city score
 type OfferWithScore struct {
 	common.Offer
+	// Match is the percentage of the offer requirements the student meets, on recommendations only.
+	Match      *int              `json:"match,omitempty"`
 	Scores     *common.CityScore `json:"scores,omitempty"`
 	LatestNews []NewsTitle       `json:"latest_news,omitempty"`
+	// Recommendation explains the rank of the offer, on recommendations only.
+	Recommendation *Recommendation `json:"recommendation,omitempty"`
+}
+
+// OfferWithScorePage is one page of gateway offers; NextCursor is passed back to fetch the next one.
+type OfferWithScorePage struct {
+	Offers     []*OfferWithScore `json:"offers"`
+	NextCursor string            `json:"next_cursor,omitempty"`
 }
 
 // NewsTitle represents just the title of a news article
@@ -239,7 +364,7 @@ type cityIntelligence struct {
 	LatestNews []NewsTitle
 }
 
-// fetchCityIntelligence loads MI8 data once per unique city using bounded parallel calls.
+// fetchCityIntelligence loads MI8 data once per unique city from cityCache, using bounded parallel calls on misses.
 func fetchCityIntelligence(ctx context.Context, offers []common.Offer) map[string]cityIntelligence {
 	uniqueCities := make(map[string]struct{})
 	for _, offer := range offers {
@@ -267,27 +392,8 @@ func fetchCityIntelligence(ctx context.Context, offers []common.Offer) map[strin
 			defer wg.Done()
 
 			sem <- struct{}{}
-			score, scoreErr := getCityScoresFromMI8(ctx, city)
-			<-sem
-			if scoreErr != nil {
-				log.Printf("mi8 city scores unavailable for city=%s: %v", city, scoreErr)
-			}
-
-			sem <- struct{}{}
-			news, newsErr := getNewsFromMI8(ctx, city)
+			intel := cityCache.get(ctx, city)
 			<-sem
-			if newsErr != nil {
-				log.Printf("mi8 news unavailable for city=%s: %v", city, newsErr)
-			}
-
-			intel := cityIntelligence{Scores: score}
-			if newsErr == nil {
-				titles := make([]NewsTitle, 0, len(news))
-				for _, n := range news {
-					titles = append(titles, NewsTitle{Title: n.Title})
-				}
-				intel.LatestNews = titles
-			}
 
 			mu.Lock()
 			cityData[city] = intel
@@ -299,71 +405,120 @@ func fetchCityIntelligence(ctx context.Context, offers []common.Offer) map[strin
 	return cityData
 }
 
-// buildOffersURL forwards supported filters to Erasmumu.
-func buildOffersURL(baseURL, city string) (string, error) {
-	parsedURL, err := url.Parse(baseURL)
-	if err != nil {
-		return "", fmt.Errorf("invalid erasmumu url: %w", err)
-	}
+// offerFilterParams are the GET /offers query parameters forwarded to Erasmumu.
+var offerFilterParams = []string{
+	"city", "domain", "min_salary", "max_salary",
+	"start_after", "start_before", "end_after", "end_before",
+	"available", "q", "sort", "order", "cursor", "limit",
+	"near", "radius_km", "employer_id",
+}
 
-	parsedURL.Path = "/offers"
-	query := parsedURL.Query()
-	if city != "" {
-		query.Set("city", city)
+// forwardOfferFilters copies supported offer filters from an incoming query.
+func forwardOfferFilters(source url.Values) url.Values {
+	filters := url.Values{}
+	for _, key := range offerFilterParams {
+		if value := source.Get(key); value != "" {
+			filters.Set(key, value)
+		}
 	}
-	parsedURL.RawQuery = query.Encode()
-
-	return parsedURL.String(), nil
+	return filters
 }
 
-// getOffersGateway handles GET /offers - Gateway endpoint to fetch offers from Erasmumu with city scores
-func getOffersGateway(w http.ResponseWriter, r *http.Request) error {
-	limit := 10
-	if limitValue := r.URL.Query().Get("limit"); limitValue != "" {
-		if parsedLimit, err := strconv.Atoi(limitValue); err == nil {
-			limit = parsedLimit
+// listOffersRequest turns forwarded offer filters into an Erasmumu ListOffers request.
+func listOffersRequest(filters url.Values) (*proto.ListOffersRequest, error) {
+	req := &proto.ListOffersRequest{
+		City:        filters.Get("city"),
+		Domain:      filters.Get("domain"),
+		StartAfter:  filters.Get("start_after"),
+		StartBefore: filters.Get("start_before"),
+		EndAfter:    filters.Get("end_after"),
+		EndBefore:   filters.Get("end_before"),
+		Q:           filters.Get("q"),
+		Sort:        filters.Get("sort"),
+		Order:       filters.Get("order"),
+		Cursor:      filters.Get("cursor"),
+		Near:        filters.Get("near"),
+	}
+
+	ints := []struct {
+		key    string
+		target **int32
+	}{
+		{"employer_id", &req.EmployerId},
+		{"min_salary", &req.MinSalary},
+		{"max_salary", &req.MaxSalary},
+	}
+	for _, i := range ints {
+		if raw := filters.Get(i.key); raw != "" {
+			value, err := strconv.ParseInt(raw, 10, 32)
+			if err != nil {
+				return nil, apierror.Validation("invalid_query", "invalid %s: expected an integer", i.key)
+			}
+			v := int32(value)
+			*i.target = &v
 		}
 	}
-	city := r.URL.Query().Get("city")
-	domain := r.URL.Query().Get("domain")
 
-	erasmumuURL := getEnv("ERASMUMU_URL", "http://erasmumu:8081")
-	offersURL, err := buildOffersURL(erasmumuURL, city)
-	if err != nil {
-		return err
+	if raw := filters.Get("available"); raw != "" {
+		available, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, apierror.Validation("invalid_query", "invalid available: expected true or false")
+		}
+		req.Available = &available
 	}
 
-	resp, err := http.Get(offersURL)
-	if err != nil {
-		log.Printf("erasmumu unavailable for /offers: %v", err)
-		return NewResponseWriter(w).JSON(http.StatusOK, []*OfferWithScore{})
+	if raw := filters.Get("radius_km"); raw != "" {
+		radius, err := strconv.ParseFloat(raw, 64)
+		if err != nil || radius <= 0 {
+			return nil, apierror.Validation("invalid_query", "invalid radius_km: expected a positive number")
+		}
+		req.RadiusKm = radius
 	}
-	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("erasmumu returned non-OK status for /offers: %d", resp.StatusCode)
-		return NewResponseWriter(w).JSON(http.StatusOK, []*OfferWithScore{})
+	if raw := filters.Get("limit"); raw != "" {
+		limit, err := strconv.ParseInt(raw, 10, 32)
+		if err != nil || limit <= 0 {
+			return nil, apierror.Validation("invalid_query", "invalid limit: expected a positive integer")
+		}
+		req.Limit = int32(limit)
 	}
 
-	var offers []common.Offer
-	if err := json.NewDecoder(resp.Body).Decode(&offers); err != nil {
-		return fmt.Errorf("failed to decode offers response: %w", err)
+	return req, nil
+}
+
+// fetchOffersPage loads one page of offers from Erasmumu.
+func fetchOffersPage(ctx context.Context, filters url.Values) (common.OfferPage, error) {
+	req, err := listOffersRequest(filters)
+	if err != nil {
+		return common.OfferPage{}, err
 	}
+	return getErasmumuClient().ListOffers(ctx, req)
+}
 
-	filteredOffers := make([]common.Offer, 0, len(offers))
-	for _, offer := range offers {
-		if domain != "" && offer.Domain != domain {
-			continue
+// fetchAllOffers follows Erasmumu cursors until every offer matching filters is loaded.
+func fetchAllOffers(ctx context.Context, filters url.Values) ([]common.Offer, error) {
+	filters.Set("limit", "100")
+
+	var offers []common.Offer
+	for {
+		page, err := fetchOffersPage(ctx, filters)
+		if err != nil {
+			return nil, err
 		}
-		filteredOffers = append(filteredOffers, offer)
-		if len(filteredOffers) == limit {
-			break
+		offers = append(offers, page.Offers...)
+
+		if page.NextCursor == "" {
+			return offers, nil
 		}
+		filters.Set("cursor", page.NextCursor)
 	}
+}
 
-	cityData := fetchCityIntelligence(r.Context(), filteredOffers)
-	offersWithScores := make([]*OfferWithScore, 0, len(filteredOffers))
-	for _, offer := range filteredOffers {
+// attachCityIntelligence pairs each offer with the MI8 scores and news of its city.
+func attachCityIntelligence(ctx context.Context, offers []common.Offer) []*OfferWithScore {
+	cityData := fetchCityIntelligence(ctx, offers)
+	offersWithScores := make([]*OfferWithScore, 0, len(offers))
+	for _, offer := range offers {
 		offerWithScore := &OfferWithScore{Offer: offer}
 		if intel, exists := cityData[offer.City]; exists {
 			offerWithScore.Scores = intel.Scores
@@ -371,20 +526,37 @@ func getOffersGateway(w http.ResponseWriter, r *http.Request) error {
 		}
 		offersWithScores = append(offersWithScores, offerWithScore)
 	}
+	return offersWithScores
+}
+
+// getOffersGateway handles GET /offers - Gateway endpoint to fetch offers from Erasmumu with city scores
+func getOffersGateway(w http.ResponseWriter, r *http.Request) error {
+	filters := forwardOfferFilters(r.URL.Query())
+	if filters.Get("limit") == "" {
+		filters.Set("limit", "10")
+	}
+
+	page, err := fetchOffersPage(r.Context(), filters)
+	if err != nil {
+		return err
+	}
 
-	return NewResponseWriter(w).JSON(http.StatusOK, offersWithScores)
+	return NewResponseWriter(w).JSON(http.StatusOK, OfferWithScorePage{
+		Offers:     attachCityIntelligence(r.Context(), page.Offers),
+		NextCursor: page.NextCursor,
+	})
 }
 
 // getCityScoresGateway handles GET /city-scores - Gateway endpoint to fetch city scores from MI8 via gRPC
 func getCityScoresGateway(w http.ResponseWriter, r *http.Request) error {
 	city := r.URL.Query().Get("city")
 	if city == "" {
-		return fmt.Errorf("city query parameter is required")
+		return apierror.Validation("invalid_query", "city query parameter is required")
 	}
 
 	cityScore, err := getCityScoresFromMI8(r.Context(), city)
 	if err != nil {
-		return fmt.Errorf("failed to fetch scores from mi8: %w", err)
+		return apierror.UpstreamUnavailable("mi8_unavailable", "failed to fetch scores from mi8", err)
 	}
 
 	var scores []common.CityScore
@@ -395,38 +567,19 @@ func getCityScoresGateway(w http.ResponseWriter, r *http.Request) error {
 	return NewResponseWriter(w).JSON(http.StatusOK, scores)
 }
 
-// getSortScore extracts the selected sortable score from an offer.
-func getSortScore(offer *OfferWithScore, sortBy string) float64 {
-	if offer == nil || offer.Scores == nil {
-		return 0
-	}
-
-	switch sortBy {
-	case "safety":
-		return offer.Scores.Safety
-	case "economy":
-		return offer.Scores.Economy
-	case "qol":
-		fallthrough
-	case "quality_of_life":
-		return offer.Scores.QoL
-	case "culture":
-		return offer.Scores.Culture
-	default:
-		return 0
-	}
-}
-
 // getRecommendedOffers handles GET /students/{id}/recommended-offers.
 func getRecommendedOffers(w http.ResponseWriter, r *http.Request) error {
-	studentID := mux.Vars(r)["id"]
+	studentID, err := studentIDFromRequest(r)
+	if err != nil {
+		return err
+	}
 
 	var student Student
-	query := "SELECT id, name, domain FROM students WHERE id = $1"
-	err := db.QueryRow(query, studentID).Scan(&student.ID, &student.Name, &student.Domain)
+	query := "SELECT " + studentColumns + " FROM students WHERE id = $1"
+	err = scanStudent(db.QueryRow(query, studentID), &student)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("student with id %s not found", studentID)
+			return studentNotFound(studentID)
 		}
 		return fmt.Errorf("failed to get student: %w", err)
 	}
@@ -437,47 +590,44 @@ func getRecommendedOffers(w http.ResponseWriter, r *http.Request) error {
 			limit = parsedLimit
 		}
 	}
-	sortBy := r.URL.Query().Get("sort_by")
 
-	erasmumuURL := getEnv("ERASMUMU_URL", "http://erasmumu:8081")
-	resp, err := http.Get(erasmumuURL + "/offers")
+	preferences, err := getStudentPreferences(studentID)
 	if err != nil {
-		log.Printf("erasmumu unavailable for /students/%s/recommended-offers: %v", studentID, err)
-		return NewResponseWriter(w).JSON(http.StatusOK, []*OfferWithScore{})
+		return err
 	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("erasmumu returned non-OK status for /students/%s/recommended-offers: %d", studentID, resp.StatusCode)
-		return NewResponseWriter(w).JSON(http.StatusOK, []*OfferWithScore{})
+	settings, err := resolveRecommendationSettings(r.URL.Query(), preferences)
+	if err != nil {
+		return err
 	}
 
-	var offers []common.Offer
-	if err := json.NewDecoder(resp.Body).Decode(&offers); err != nil {
-		return fmt.Errorf("failed to decode offers response: %w", err)
-	}
+	filters := forwardOfferFilters(r.URL.Query())
+	filters.Del("cursor")
+	filters.Set("domain", student.Domain)
+	// Expired offers are closed by Erasmumu's scheduler; the start date filter also hides
+	// offers that became stale since its last run.
+	filters.Set("available", "true")
+	filters.Set("start_after", time.Now().UTC().Format("2006-01-02"))
 
-	matchingOffers := make([]common.Offer, 0, len(offers))
-	for _, offer := range offers {
-		if offer.Domain == student.Domain {
-			matchingOffers = append(matchingOffers, offer)
-		}
+	matchingOffers, err := fetchAllOffers(r.Context(), filters)
+	if err != nil {
+		return err
 	}
 
-	cityData := fetchCityIntelligence(r.Context(), matchingOffers)
-	recommendedOffers := make([]*OfferWithScore, 0, len(matchingOffers))
+	// Offers whose mandatory requirements the student lacks cannot be applied to.
+	eligibleOffers := make([]common.Offer, 0, len(matchingOffers))
 	for _, offer := range matchingOffers {
-		recommendedOffer := &OfferWithScore{Offer: offer}
-		if intel, exists := cityData[offer.City]; exists {
-			recommendedOffer.Scores = intel.Scores
-			recommendedOffer.LatestNews = intel.LatestNews
+		if len(missingRequirements(student, offer)) == 0 {
+			eligibleOffers = append(eligibleOffers, offer)
 		}
-		recommendedOffers = append(recommendedOffers, recommendedOffer)
 	}
 
-	sort.Slice(recommendedOffers, func(i, j int) bool {
-		return getSortScore(recommendedOffers[i], sortBy) > getSortScore(recommendedOffers[j], sortBy)
-	})
+	recommendedOffers := attachCityIntelligence(r.Context(), eligibleOffers)
+	for _, offer := range recommendedOffers {
+		match := matchOffer(student, offer.Offer)
+		offer.Match = &match
+	}
+
+	rankOffers(recommendedOffers, settings, time.Now().UTC())
 
 	if limit >= 0 && len(recommendedOffers) > limit {
 		recommendedOffers = recommendedOffers[:limit]
@@ -488,13 +638,13 @@ func getRecommendedOffers(w http.ResponseWriter, r *http.Request) error {
 
 // getStudentNotifications handles GET /students/{id}/notifications.
 func getStudentNotifications(w http.ResponseWriter, r *http.Request) error {
-	studentID, err := strconv.Atoi(mux.Vars(r)["id"])
-	if err != nil || studentID <= 0 {
-		return fmt.Errorf("invalid student id")
+	studentID, err := studentIDFromRequest(r)
+	if err != nil {
+		return err
 	}
 
 	rows, err := db.Query(
-		"SELECT id, student_id, type, offer_id, message, read, TO_CHAR(created_at, 'YYYY-MM-DD\"T\"HH24:MI:SS\"Z\"') FROM notifications WHERE student_id = $1 ORDER BY created_at DESC, id DESC",
+		"SELECT id, student_id, type, offer_id, message, read, obsolete, TO_CHAR(created_at, 'YYYY-MM-DD\"T\"HH24:MI:SS\"Z\"') FROM notifications WHERE student_id = $1 ORDER BY created_at DESC, id DESC",
 		studentID,
 	)
 	if err != nil {
@@ -512,6 +662,7 @@ func getStudentNotifications(w http.ResponseWriter, r *http.Request) error {
 			&notification.OfferID,
 			&notification.Message,
 			&notification.Read,
+			&notification.Obsolete,
 			&notification.CreatedAt,
 		); err != nil {
 			return fmt.Errorf("failed to scan notification: %w", err)
@@ -530,13 +681,14 @@ func getStudentNotifications(w http.ResponseWriter, r *http.Request) error {
 func markNotificationAsRead(w http.ResponseWriter, r *http.Request) error {
 	notificationID, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil || notificationID <= 0 {
-		return fmt.Errorf("invalid notification id")
+		return apierror.Validation("invalid_id", "invalid notification id")
 	}
 
+	// Students only reach their own notifications; others' look missing.
 	var notification Notification
 	err = db.QueryRow(
-		"UPDATE notifications SET read = true WHERE id = $1 RETURNING id, student_id, type, offer_id, message, read, TO_CHAR(created_at, 'YYYY-MM-DD\"T\"HH24:MI:SS\"Z\"')",
-		notificationID,
+		"UPDATE notifications SET read = true WHERE id = $1 AND ($2 = 0 OR student_id = $2) RETURNING id, student_id, type, offer_id, message, read, obsolete, TO_CHAR(created_at, 'YYYY-MM-DD\"T\"HH24:MI:SS\"Z\"')",
+		notificationID, studentScope(r.Context()),
 	).Scan(
 		&notification.ID,
 		&notification.StudentID,
@@ -544,11 +696,12 @@ func markNotificationAsRead(w http.ResponseWriter, r *http.Request) error {
 		&notification.OfferID,
 		&notification.Message,
 		&notification.Read,
+		&notification.Obsolete,
 		&notification.CreatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("notification with id %d not found", notificationID)
+			return apierror.NotFound("notification_not_found", "notification with id %d not found", notificationID)
 		}
 		return fmt.Errorf("failed to update notification: %w", err)
 	}
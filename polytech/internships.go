@@ -0,0 +1,804 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+
+	"github.com/thomasrubini/polymove/common"
+	"github.com/thomasrubini/polymove/common/apierror"
+)
+
+const (
+	internshipStatusApplied   = "applied"
+	internshipStatusAccepted  = "accepted"
+	internshipStatusRejected  = "rejected"
+	internshipStatusSigned    = "signed"
+	internshipStatusOngoing   = "ongoing"
+	internshipStatusCompleted = "completed"
+	internshipStatusCancelled = "cancelled"
+
+	notificationTypeInternshipStatus = "internship_status"
+
+	// seatReleaseInterval is how often seats Erasmumu could not release right away are retried.
+	seatReleaseInterval  = time.Minute
+	seatReleaseBatchSize = 100
+
+	defaultInternshipsPageSize = 20
+	maxInternshipsPageSize     = 100
+
+	// internshipBackfillRetryInterval is how often the offers of older internships are asked again while
+	// Erasmumu cannot answer.
+	internshipBackfillRetryInterval = time.Minute
+)
+
+// internshipTransitions lists the statuses each status can move to; rejected, completed and cancelled are final.
+var internshipTransitions = map[string][]string{
+	internshipStatusApplied:  {internshipStatusAccepted, internshipStatusRejected, internshipStatusCancelled},
+	internshipStatusAccepted: {internshipStatusSigned, internshipStatusCancelled},
+	internshipStatusSigned:   {internshipStatusOngoing, internshipStatusCancelled},
+	internshipStatusOngoing:  {internshipStatusCompleted, internshipStatusCancelled},
+}
+
+// InternshipStatusChange is one timestamped step of an internship history; From is empty for the initial status.
+type InternshipStatusChange struct {
+	From      string `json:"from,omitempty"`
+	To        string `json:"to"`
+	Reason    string `json:"reason,omitempty"`
+	ChangedAt string `json:"changed_at"`
+}
+
+// InternshipStatusRequest is the payload of PUT /internship/{id}/status.
+type InternshipStatusRequest struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+// InvalidTransitionError rejects a status change the internship lifecycle does not allow.
+type InvalidTransitionError struct {
+	InternshipID int
+	From         string
+	To           string
+}
+
+func (e *InvalidTransitionError) Is(target error) bool { return target == apierror.ErrConflict }
+
+func (e *InvalidTransitionError) ErrorCode() string { return "invalid_transition" }
+
+func (e *InvalidTransitionError) Error() string {
+	allowed := internshipTransitions[e.From]
+	if len(allowed) == 0 {
+		return fmt.Sprintf("internship %d is %s and can no longer change status", e.InternshipID, e.From)
+	}
+	return fmt.Sprintf("internship %d cannot go from %s to %s, expected one of: %s", e.InternshipID, e.From, e.To, strings.Join(allowed, ", "))
+}
+
+// InternshipConflictError rejects an internship clashing with an active one of the same student.
+type InternshipConflictError struct {
+	StudentID     int
+	ConflictingID int
+	Reason        string
+}
+
+func (e *InternshipConflictError) Is(target error) bool { return target == apierror.ErrConflict }
+
+func (e *InternshipConflictError) ErrorCode() string { return "internship_conflict" }
+
+func (e *InternshipConflictError) Error() string {
+	if e.ConflictingID == 0 {
+		return fmt.Sprintf("student %d already has an active internship %s", e.StudentID, e.Reason)
+	}
+	return fmt.Sprintf("student %d already has internship %d %s", e.StudentID, e.ConflictingID, e.Reason)
+}
+
+// findConflictingInternship returns the active internship of a student for the same offer or overlapping
+// start..end, if any. It only gives a friendly early answer: the internships_active_offer_idx index and the
+// internships_no_overlap constraint enforce the rule for concurrent requests.
+func findConflictingInternship(studentID, offerID int, start, end string) (*InternshipConflictError, error) {
+	var (
+		id        int
+		sameOffer bool
+		startDate string
+		endDate   string
+	)
+	err := db.QueryRow(`
+		SELECT id, offer_id = $2, COALESCE(TO_CHAR(start_date, 'YYYY-MM-DD'), ''), COALESCE(TO_CHAR(end_date, 'YYYY-MM-DD'), '')
+		FROM internships
+		WHERE student_id = $1 AND status NOT IN ($5, $6)
+			AND (offer_id = $2 OR daterange(start_date, end_date, '[]') && daterange($3::date, $4::date, '[]'))
+		ORDER BY offer_id = $2 DESC, id
+		LIMIT 1`,
+		studentID, offerID, start, end, internshipStatusRejected, internshipStatusCancelled,
+	).Scan(&id, &sameOffer, &startDate, &endDate)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up conflicting internships: %w", err)
+	}
+
+	conflict := &InternshipConflictError{StudentID: studentID, ConflictingID: id}
+	if sameOffer {
+		conflict.Reason = fmt.Sprintf("for offer %d", offerID)
+	} else {
+		conflict.Reason = fmt.Sprintf("from %s to %s overlapping %s to %s", startDate, endDate, start, end)
+	}
+	return conflict, nil
+}
+
+// internshipGuardViolation tells whether err comes from the internships_active_offer_idx index or the
+// internships_no_overlap constraint.
+func internshipGuardViolation(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return (pqErr.Code == "23505" && pqErr.Constraint == "internships_active_offer_idx") ||
+		(pqErr.Code == "23P01" && pqErr.Constraint == "internships_no_overlap")
+}
+
+// knownInternshipStatus tells whether status is part of the lifecycle.
+func knownInternshipStatus(status string) bool {
+	if status == internshipStatusApplied {
+		return true
+	}
+	for _, next := range internshipTransitions {
+		for _, candidate := range next {
+			if candidate == status {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// canTransition tells whether an internship may move from one status to another.
+func canTransition(from, to string) bool {
+	for _, next := range internshipTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// recordInternshipStatusChange appends one step to the internship history inside tx.
+func recordInternshipStatusChange(tx *sql.Tx, internshipID int, from, to, reason string) (InternshipStatusChange, error) {
+	change := InternshipStatusChange{From: from, To: to, Reason: reason}
+
+	var fromStatus interface{}
+	if from != "" {
+		fromStatus = from
+	}
+
+	err := tx.QueryRow(
+		"INSERT INTO internship_status_changes (internship_id, from_status, to_status, reason) VALUES ($1, $2, $3, $4) RETURNING TO_CHAR(changed_at, 'YYYY-MM-DD\"T\"HH24:MI:SS\"Z\"')",
+		internshipID, fromStatus, to, reason,
+	).Scan(&change.ChangedAt)
+	if err != nil {
+		return change, fmt.Errorf("failed to record internship status change: %w", err)
+	}
+	return change, nil
+}
+
+// getInternshipHistory lists the status changes of an internship, oldest first.
+func getInternshipHistory(internshipID int) ([]InternshipStatusChange, error) {
+	rows, err := db.Query(
+		"SELECT COALESCE(from_status, ''), to_status, reason, TO_CHAR(changed_at, 'YYYY-MM-DD\"T\"HH24:MI:SS\"Z\"') FROM internship_status_changes WHERE internship_id = $1 ORDER BY id",
+		internshipID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query internship history: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	history := []InternshipStatusChange{}
+	for rows.Next() {
+		var change InternshipStatusChange
+		if err := rows.Scan(&change.From, &change.To, &change.Reason, &change.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan internship status change: %w", err)
+		}
+		history = append(history, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed iterating internship history: %w", err)
+	}
+	return history, nil
+}
+
+// internshipStatusMessage renders the text of an internship_status notification.
+func internshipStatusMessage(internship Internship) string {
+	return fmt.Sprintf("Your internship application for offer %d is now %s.", internship.OfferID, internship.Status)
+}
+
+// internshipColumns lists the internships columns in the order expected by scanInternship.
+const internshipColumns = `id, student_id, offer_id, COALESCE(reservation_id, 0), status,
+	TO_CHAR(status_changed_at, 'YYYY-MM-DD"T"HH24:MI:SS"Z"'), COALESCE(TO_CHAR(created_at, 'YYYY-MM-DD"T"HH24:MI:SS"Z"'), '')`
+
+// scanInternship reads one internship selected with internshipColumns.
+func scanInternship(row interface{ Scan(...interface{}) error }, internship *Internship) error {
+	return row.Scan(&internship.ID, &internship.StudentID, &internship.OfferID, &internship.ReservationID,
+		&internship.Status, &internship.StatusChangedAt, &internship.CreatedAt)
+}
+
+func internshipNotFound(id int) error {
+	return apierror.NotFound("internship_not_found", "internship with id %d not found", id)
+}
+
+// internshipIDFromRequest parses the {id} route variable of internship routes.
+func internshipIDFromRequest(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		return 0, apierror.Validation("invalid_id", "invalid internship id")
+	}
+	return id, nil
+}
+
+// changeInternshipStatus moves an internship to another status, records the step, notifies the student
+// and publishes internship.status_changed. Rejected and cancelled internships give their seat back
+// to the Erasmumu offer.
+func changeInternshipStatus(ctx context.Context, id int, to, reason string) (Internship, error) {
+	var internship Internship
+
+	tx, err := db.Begin()
+	if err != nil {
+		return internship, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	err = scanInternship(tx.QueryRow("SELECT "+internshipColumns+" FROM internships WHERE id = $1 FOR UPDATE", id), &internship)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return internship, internshipNotFound(id)
+		}
+		return internship, fmt.Errorf("failed to get internship: %w", err)
+	}
+
+	from := internship.Status
+	if !canTransition(from, to) {
+		return internship, &InvalidTransitionError{InternshipID: id, From: from, To: to}
+	}
+
+	releaseSeat := (to == internshipStatusRejected || to == internshipStatusCancelled) && internship.ReservationID != 0
+
+	change, err := recordInternshipStatusChange(tx, id, from, to, reason)
+	if err != nil {
+		return internship, err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE internships SET status = $1, status_changed_at = CURRENT_TIMESTAMP, seat_release_pending = seat_release_pending OR $3 WHERE id = $2",
+		to, id, releaseSeat,
+	)
+	if err != nil {
+		return internship, fmt.Errorf("failed to update internship status: %w", err)
+	}
+	internship.Status = to
+	internship.StatusChangedAt = change.ChangedAt
+
+	_, err = tx.Exec(
+		"INSERT INTO notifications (student_id, type, offer_id, message, read) VALUES ($1, $2, $3, $4, false) ON CONFLICT (student_id, offer_id, type) DO UPDATE SET message = EXCLUDED.message, read = false, obsolete = false, created_at = CURRENT_TIMESTAMP",
+		internship.StudentID,
+		notificationTypeInternshipStatus,
+		internship.OfferID,
+		internshipStatusMessage(internship),
+	)
+	if err != nil {
+		return internship, fmt.Errorf("failed to insert internship notification: %w", err)
+	}
+
+	if err := enqueueInternshipStatusChangedEvent(tx, internship, change); err != nil {
+		return internship, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return internship, fmt.Errorf("failed to commit internship status: %w", err)
+	}
+	outbox.Notify()
+
+	log.Printf("Internship id=%d moved from %s to %s", id, from, internship.Status)
+
+	// The seat is only given back once the change is committed, so a failed change never frees it. When
+	// Erasmumu cannot release it now, runSeatReleaser retries.
+	if releaseSeat {
+		if err := releaseInternshipSeat(context.WithoutCancel(ctx), id, internship.ReservationID); err != nil {
+			log.Printf("Seat of internship id=%d not released yet: %v", id, err)
+		}
+	}
+
+	return internship, nil
+}
+
+// releaseInternshipSeat gives the seat of a rejected or cancelled internship back to Erasmumu, then clears its
+// seat_release_pending flag. Releasing is idempotent in Erasmumu, and a reservation it no longer knows has no
+// seat to give back.
+func releaseInternshipSeat(ctx context.Context, id, reservationID int) error {
+	if _, err := getErasmumuClient().ReleaseSeat(ctx, reservationID); err != nil && !errors.Is(err, apierror.ErrNotFound) {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE internships SET seat_release_pending = false WHERE id = $1", id); err != nil {
+		return fmt.Errorf("failed to clear seat release of internship %d: %w", id, err)
+	}
+	return nil
+}
+
+// runSeatReleaser retries the seat releases Erasmumu could not take when internships were rejected or cancelled.
+func runSeatReleaser(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := releasePendingSeats(context.Background()); err != nil {
+			log.Printf("Seat release retry failed: %v", err)
+		}
+	}
+}
+
+// releasePendingSeats releases up to seatReleaseBatchSize pending seats, oldest internships first, and stops at
+// the first failure until the next tick.
+func releasePendingSeats(ctx context.Context) error {
+	rows, err := db.QueryContext(ctx,
+		"SELECT id, COALESCE(reservation_id, 0) FROM internships WHERE seat_release_pending ORDER BY id LIMIT $1",
+		seatReleaseBatchSize,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query pending seat releases: %w", err)
+	}
+
+	type pendingRelease struct{ id, reservationID int }
+	var pending []pendingRelease
+	for rows.Next() {
+		var release pendingRelease
+		if err := rows.Scan(&release.id, &release.reservationID); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to scan pending seat release: %w", err)
+		}
+		pending = append(pending, release)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed iterating pending seat releases: %w", err)
+	}
+
+	for _, release := range pending {
+		if err := releaseInternshipSeat(ctx, release.id, release.reservationID); err != nil {
+			return err
+		}
+		log.Printf("Released seat of internship id=%d", release.id)
+	}
+	return nil
+}
+
+// enrichInternships attaches the Erasmumu offer and the MI8 city scores of each internship, like createInternship.
+// Internships keep a nil offer when Erasmumu no longer knows the offer; errErasmumuUnavailable is returned when
+// Erasmumu cannot answer.
+func enrichInternships(ctx context.Context, internships []Internship) error {
+	ids := make([]int, 0, len(internships))
+	for _, internship := range internships {
+		ids = append(ids, internship.OfferID)
+	}
+
+	offers, err := getErasmumuClient().GetOffers(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	found := make([]common.Offer, 0, len(offers))
+	for _, offer := range offers {
+		found = append(found, offer)
+	}
+	cityData := fetchCityIntelligence(ctx, found)
+
+	for i := range internships {
+		offer, ok := offers[internships[i].OfferID]
+		if !ok {
+			continue
+		}
+		internships[i].Offer = &offer
+		if intel, exists := cityData[offer.City]; exists {
+			internships[i].CityScore = intel.Scores
+		}
+	}
+	return nil
+}
+
+// queryInternships lists the internships matching a SQL condition, newest first.
+func queryInternships(condition string, args ...interface{}) ([]Internship, error) {
+	return queryInternshipPage(condition, 0, args...)
+}
+
+// queryInternshipPage lists at most limit internships matching a SQL condition, newest first; 0 means no limit.
+func queryInternshipPage(condition string, limit int, args ...interface{}) ([]Internship, error) {
+	query := "SELECT " + internshipColumns + " FROM internships"
+	if condition != "" {
+		query += " WHERE " + condition
+	}
+	query += " ORDER BY id DESC"
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query internships: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	internships := []Internship{}
+	for rows.Next() {
+		var internship Internship
+		if err := scanInternship(rows, &internship); err != nil {
+			return nil, fmt.Errorf("failed to scan internship: %w", err)
+		}
+		internships = append(internships, internship)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed iterating internships: %w", err)
+	}
+	return internships, nil
+}
+
+// internshipFilter holds the GET /internships filters on the offer city, domain and period stored with each
+// internship.
+type internshipFilter struct {
+	Domain      string
+	City        string
+	StartAfter  string
+	StartBefore string
+	EndAfter    string
+	EndBefore   string
+}
+
+// parseInternshipFilter reads the offer filters of GET /internships; dates are YYYY-MM-DD and inclusive.
+func parseInternshipFilter(values url.Values) (internshipFilter, error) {
+	filter := internshipFilter{
+		Domain:      strings.TrimSpace(values.Get("domain")),
+		City:        strings.TrimSpace(values.Get("city")),
+		StartAfter:  values.Get("start_after"),
+		StartBefore: values.Get("start_before"),
+		EndAfter:    values.Get("end_after"),
+		EndBefore:   values.Get("end_before"),
+	}
+	for key, value := range map[string]string{
+		"start_after": filter.StartAfter, "start_before": filter.StartBefore,
+		"end_after": filter.EndAfter, "end_before": filter.EndBefore,
+	} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return filter, apierror.Validation("invalid_query", "invalid %s: expected YYYY-MM-DD", key)
+		}
+	}
+	return filter, nil
+}
+
+// active tells whether the filter looks at the offers of the internships.
+func (f internshipFilter) active() bool {
+	return f != internshipFilter{}
+}
+
+// InternshipPage is one page of GET /internships; NextCursor is passed back to fetch the next one.
+type InternshipPage struct {
+	Internships []Internship `json:"internships"`
+	NextCursor  string       `json:"next_cursor,omitempty"`
+}
+
+// internshipCursor is the position after the last internship of a page, newest first.
+type internshipCursor struct {
+	ID int `json:"id"`
+}
+
+func (c internshipCursor) encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeInternshipCursor parses an opaque cursor produced by internshipCursor.encode.
+func decodeInternshipCursor(raw string) (*internshipCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, apierror.Validation("invalid_query", "invalid cursor")
+	}
+
+	var cursor internshipCursor
+	if err := json.Unmarshal(decoded, &cursor); err != nil || cursor.ID <= 0 {
+		return nil, apierror.Validation("invalid_query", "invalid cursor")
+	}
+	return &cursor, nil
+}
+
+// runInternshipOfferBackfill fills the offer of the internships created before it was stored, retrying until
+// Erasmumu answers. New internships store their offer, so it stops once done.
+func runInternshipOfferBackfill(interval time.Duration) {
+	for {
+		err := backfillInternshipOffers(context.Background())
+		if err == nil {
+			return
+		}
+		log.Printf("Internship offer backfill failed, retrying in %s: %v", interval, err)
+		time.Sleep(interval)
+	}
+}
+
+// backfillInternshipOffers copies the offer city, domain and period from Erasmumu into the internships created
+// before they were stored. A period clashing with another active internship of the student is left empty: such
+// internships predate the overlap guard and never match date filters.
+func backfillInternshipOffers(ctx context.Context) error {
+	rows, err := db.QueryContext(ctx, "SELECT id, offer_id FROM internships WHERE city IS NULL OR domain IS NULL")
+	if err != nil {
+		return fmt.Errorf("failed to query internships without offer location: %w", err)
+	}
+
+	offerIDs := map[int]int{}
+	ids := []int{}
+	for rows.Next() {
+		var id, offerID int
+		if err := rows.Scan(&id, &offerID); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to scan internship: %w", err)
+		}
+		offerIDs[id] = offerID
+		ids = append(ids, offerID)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed iterating internships without offer location: %w", err)
+	}
+	if len(offerIDs) == 0 {
+		return nil
+	}
+
+	offers, err := getErasmumuClient().GetOffers(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for id, offerID := range offerIDs {
+		offer, ok := offers[offerID]
+		if _, err := db.ExecContext(ctx, "UPDATE internships SET city = $2, domain = $3 WHERE id = $1", id, offer.City, offer.Domain); err != nil {
+			return fmt.Errorf("failed to backfill offer location of internship %d: %w", id, err)
+		}
+		if !ok || offer.StartDate == "" || offer.EndDate == "" {
+			continue
+		}
+		_, err := db.ExecContext(ctx,
+			"UPDATE internships SET start_date = $2, end_date = $3 WHERE id = $1 AND start_date IS NULL",
+			id, offer.StartDate, offer.EndDate,
+		)
+		if internshipGuardViolation(err) {
+			log.Printf("Internship id=%d clashes with another active internship, its period is left empty", id)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to backfill period of internship %d: %w", id, err)
+		}
+	}
+	log.Printf("Backfilled the offer of %d internships", len(offerIDs))
+	return nil
+}
+
+// updateInternshipStatus handles PUT /internship/{id}/status - Moves an internship along its lifecycle
+func updateInternshipStatus(w http.ResponseWriter, r *http.Request) error {
+	id, err := internshipIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	var req InternshipStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apierror.Validation("invalid_body", "failed to decode request body: %v", err)
+	}
+	req.Status = strings.ToLower(strings.TrimSpace(req.Status))
+	if !knownInternshipStatus(req.Status) {
+		return apierror.Validation("invalid_status", "unknown internship status %q", req.Status)
+	}
+
+	internship, err := changeInternshipStatus(r.Context(), id, req.Status, strings.TrimSpace(req.Reason))
+	if err != nil {
+		return err
+	}
+
+	internship.History, err = getInternshipHistory(id)
+	if err != nil {
+		return err
+	}
+
+	return NewResponseWriter(w).JSON(http.StatusOK, internship)
+}
+
+// cancelInternship handles DELETE /internship/{id} - Cancels an internship and gives its seat back; the row
+// and its history are kept
+func cancelInternship(w http.ResponseWriter, r *http.Request) error {
+	id, err := internshipIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	if err := authorizeInternship(r.Context(), id); err != nil {
+		return err
+	}
+
+	internship, err := changeInternshipStatus(r.Context(), id, internshipStatusCancelled, strings.TrimSpace(r.URL.Query().Get("reason")))
+	if err != nil {
+		return err
+	}
+
+	internship.History, err = getInternshipHistory(id)
+	if err != nil {
+		return err
+	}
+
+	return NewResponseWriter(w).JSON(http.StatusOK, internship)
+}
+
+// getInternship handles GET /internship/{id} - Retrieves an internship with its offer, city scores and history
+func getInternship(w http.ResponseWriter, r *http.Request) error {
+	id, err := internshipIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	internships, err := queryInternships("id = $1", id)
+	if err != nil {
+		return err
+	}
+	if len(internships) == 0 {
+		return internshipNotFound(id)
+	}
+	if err := authorizeStudent(r.Context(), internships[0].StudentID); err != nil {
+		return err
+	}
+
+	if err := enrichInternships(r.Context(), internships); err != nil {
+		return err
+	}
+
+	internship := internships[0]
+	internship.History, err = getInternshipHistory(id)
+	if err != nil {
+		return err
+	}
+
+	return NewResponseWriter(w).JSON(http.StatusOK, internship)
+}
+
+// getStudentInternships handles GET /students/{id}/internships - Lists the internships of a student,
+// optionally filtered by status
+func getStudentInternships(w http.ResponseWriter, r *http.Request) error {
+	studentID, err := studentIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	if err := checkStudentExists(studentID); err != nil {
+		return err
+	}
+
+	status := r.URL.Query().Get("status")
+	if status != "" && !knownInternshipStatus(status) {
+		return apierror.Validation("invalid_status", "unknown internship status %q", status)
+	}
+
+	internships, err := queryInternships("student_id = $1 AND ($2 = '' OR status = $2)", studentID, status)
+	if err != nil {
+		return err
+	}
+
+	if err := enrichInternships(r.Context(), internships); err != nil {
+		return err
+	}
+
+	return NewResponseWriter(w).JSON(http.StatusOK, internships)
+}
+
+// getInternships handles GET /internships - Lists internships newest first, filtered by status, student_id and
+// the domain, city and start_after/start_before/end_after/end_before dates of their offer, limit per page and
+// continuing from cursor
+func getInternships(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+
+	filter, err := parseInternshipFilter(query)
+	if err != nil {
+		return err
+	}
+
+	conditions := []string{}
+	args := []interface{}{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if status := query.Get("status"); status != "" {
+		if !knownInternshipStatus(status) {
+			return apierror.Validation("invalid_status", "unknown internship status %q", status)
+		}
+		where("status = $%d", status)
+	}
+
+	if raw := query.Get("student_id"); raw != "" {
+		studentID, err := strconv.Atoi(raw)
+		if err != nil || studentID <= 0 {
+			return apierror.Validation("invalid_query", "invalid student_id")
+		}
+		where("student_id = $%d", studentID)
+	}
+
+	limit := defaultInternshipsPageSize
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			return apierror.Validation("invalid_query", "invalid limit: expected a positive integer")
+		}
+		limit = min(parsed, maxInternshipsPageSize)
+	}
+
+	if raw := query.Get("cursor"); raw != "" {
+		cursor, err := decodeInternshipCursor(raw)
+		if err != nil {
+			return err
+		}
+		where("id < $%d", cursor.ID)
+	}
+
+	if filter.active() {
+		// Until runInternshipOfferBackfill got the offers of older internships from Erasmumu, filtering would
+		// silently drop them.
+		var pending bool
+		if err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM internships WHERE city IS NULL OR domain IS NULL)").Scan(&pending); err != nil {
+			return fmt.Errorf("failed to check internship offers: %w", err)
+		}
+		if pending {
+			return fmt.Errorf("%w: offers of older internships not loaded yet", errErasmumuUnavailable)
+		}
+		if filter.Domain != "" {
+			where("LOWER(domain) = LOWER($%d)", filter.Domain)
+		}
+		if filter.City != "" {
+			where("LOWER(city) = LOWER($%d)", filter.City)
+		}
+		if filter.StartAfter != "" {
+			where("start_date >= $%d::date", filter.StartAfter)
+		}
+		if filter.StartBefore != "" {
+			where("start_date <= $%d::date", filter.StartBefore)
+		}
+		if filter.EndAfter != "" {
+			where("end_date >= $%d::date", filter.EndAfter)
+		}
+		if filter.EndBefore != "" {
+			where("end_date <= $%d::date", filter.EndBefore)
+		}
+	}
+
+	internships, err := queryInternshipPage(strings.Join(conditions, " AND "), limit+1, args...)
+	if err != nil {
+		return err
+	}
+
+	page := InternshipPage{Internships: internships}
+	if len(internships) > limit {
+		page.Internships = internships[:limit]
+		page.NextCursor = internshipCursor{ID: page.Internships[limit-1].ID}.encode()
+	}
+
+	if err := enrichInternships(r.Context(), page.Internships); err != nil {
+		return err
+	}
+
+	return NewResponseWriter(w).JSON(http.StatusOK, page)
+}
@@ -3,8 +3,10 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -12,18 +14,38 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+
+	"github.com/thomasrubini/polymove/common"
+	"github.com/thomasrubini/polymove/common/apierror"
+	"github.com/thomasrubini/polymove/common/auth"
 )
 
 type Student struct {
 	ID     int    `json:"id"`
 	Name   string `json:"name"`
 	Domain string `json:"domain"`
+	// Skills and Languages are matched against offer requirements, see matchOffer.
+	Skills    []string `json:"skills"`
+	Languages []string `json:"languages"`
 }
 
+// studentColumns lists the students columns in the order expected by scanStudent.
+const studentColumns = "id, name, domain, skills, languages"
+
+// scanStudent reads one student selected with studentColumns.
+func scanStudent(row interface{ Scan(...interface{}) error }, student *Student) error {
+	student.Skills, student.Languages = []string{}, []string{}
+	return row.Scan(&student.ID, &student.Name, &student.Domain, pq.Array(&student.Skills), pq.Array(&student.Languages))
+}
+
+// ErrorResponse is the body of every error; Code is stable for clients to branch on, Message is for humans.
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
+	Error                   string               `json:"error"`
+	Code                    string               `json:"code"`
+	Message                 string               `json:"message"`
+	ConflictingInternshipID int                  `json:"conflicting_internship_id,omitempty"`
+	Missing                 []MissingRequirement `json:"missing,omitempty"`
 }
 
 var db *sql.DB
@@ -48,11 +70,23 @@ func (rw *ResponseWriter) EncodeJSON(data interface{}) error {
 }
 
 func (rw *ResponseWriter) EncodeError(statusCode int, err error) error {
-	rw.WriteHeader(statusCode)
-	return json.NewEncoder(rw).Encode(ErrorResponse{
+	response := ErrorResponse{
 		Error:   http.StatusText(statusCode),
+		Code:    apierror.Code(err),
 		Message: err.Error(),
-	})
+	}
+
+	var conflictErr *InternshipConflictError
+	if errors.As(err, &conflictErr) {
+		response.ConflictingInternshipID = conflictErr.ConflictingID
+	}
+	var missingErr *MissingRequirementsError
+	if errors.As(err, &missingErr) {
+		response.Missing = missingErr.Missing
+	}
+
+	rw.WriteHeader(statusCode)
+	return json.NewEncoder(rw).Encode(response)
 }
 
 func (rw *ResponseWriter) NoContent() {
@@ -68,8 +102,9 @@ func errorHandler(fn func(w http.ResponseWriter, r *http.Request) error) http.Ha
 	return func(w http.ResponseWriter, r *http.Request) {
 		rw := NewResponseWriter(w)
 		if err := fn(w, r); err != nil {
-			log.Printf("Error: %v", err)
-			if err2 := rw.EncodeError(http.StatusInternalServerError, err); err2 != nil {
+			statusCode := apierror.Status(err)
+			log.Printf("Error (%d): %v", statusCode, err)
+			if err2 := rw.EncodeError(statusCode, err); err2 != nil {
 				log.Printf("Failed to send error to user: %v", err)
 			}
 		}
@@ -88,26 +123,62 @@ func main() {
 	log.SetOutput(os.Stdout)
 
 	initDB()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := common.RunMigrateCommand(context.Background(), newMigrator(), os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	migrateDB()
+
+	secret, err := auth.SecretFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	jwtSecret = secret
+	bootstrapAdmin()
+
 	initRabbitMQ()
 	defer rmqChannel.Close()
 	defer rmqConn.Close()
-	go consumeOfferCreatedEvents(rmqChannel)
+	go outbox.Relay(rabbitMQURL())
+	go runSeatReleaser(seatReleaseInterval)
+	go runInternshipOfferBackfill(internshipBackfillRetryInterval)
+	go common.ConsumeEvents(rmqChannel, common.QueuePolytechOfferCreated, common.RoutingKeyOfferCreated, processOfferCreatedEvent)
+	go common.ConsumeEvents(rmqChannel, common.QueuePolytechOfferUpdated, common.RoutingKeyOfferUpdated, processOfferUpdatedEvent)
+	go common.ConsumeEvents(rmqChannel, common.QueuePolytechOfferClosed, common.RoutingKeyOfferClosed, processOfferClosedEvent)
+	go common.ConsumeEvents(rmqChannel, common.QueuePolytechOfferDeleted, common.RoutingKeyOfferDeleted, processOfferDeletedEvent)
+	go common.ConsumeEvents(rmqChannel, common.QueuePolytechOfferExpired, common.RoutingKeyOfferExpired, processOfferExpiredEvent)
+	go common.ConsumeBroadcastEvents(rmqChannel, common.RoutingKeyCityScoreChanged, processCityScoreChangedEvent)
 
 	router := mux.NewRouter()
 	router.Use(loggingMiddleware)
-	router.HandleFunc("/student", errorHandler(createStudent)).Methods(http.MethodPost)
-	router.HandleFunc("/student/{id}", errorHandler(getStudent)).Methods(http.MethodGet)
-	router.HandleFunc("/student", errorHandler(getStudentsByDomain)).Methods(http.MethodGet)
-	router.HandleFunc("/student/{id}", errorHandler(updateStudent)).Methods(http.MethodPut)
-	router.HandleFunc("/student/{id}", errorHandler(deleteStudent)).Methods(http.MethodDelete)
-	router.HandleFunc("/students/{id}/recommended-offers", errorHandler(getRecommendedOffers)).Methods(http.MethodGet)
-	router.HandleFunc("/students/{id}/notifications", errorHandler(getStudentNotifications)).Methods(http.MethodGet)
-	router.HandleFunc("/notifications/{id}/read", errorHandler(markNotificationAsRead)).Methods(http.MethodPut)
-
-	router.HandleFunc("/internship", errorHandler(createInternship)).Methods(http.MethodPost)
+	router.HandleFunc("/auth/login", errorHandler(login)).Methods(http.MethodPost)
+	router.HandleFunc("/auth/me", errorHandler(requireRole(getCurrentUser, auth.RoleStudent, auth.RoleAdmin, auth.RoleEmployer))).Methods(http.MethodGet)
+	router.HandleFunc("/users", errorHandler(requireRole(createUser, auth.RoleAdmin))).Methods(http.MethodPost)
+
+	router.HandleFunc("/student", errorHandler(requireRole(createStudent, auth.RoleAdmin))).Methods(http.MethodPost)
+	router.HandleFunc("/student/{id}", errorHandler(requireStudentOrAdmin(getStudent))).Methods(http.MethodGet)
+	router.HandleFunc("/student", errorHandler(requireRole(getStudentsByDomain, auth.RoleAdmin))).Methods(http.MethodGet)
+	router.HandleFunc("/student/{id}", errorHandler(requireRole(updateStudent, auth.RoleAdmin))).Methods(http.MethodPut)
+	router.HandleFunc("/student/{id}", errorHandler(requireRole(deleteStudent, auth.RoleAdmin))).Methods(http.MethodDelete)
+	router.HandleFunc("/students/{id}/recommended-offers", errorHandler(requireStudentOrAdmin(getRecommendedOffers))).Methods(http.MethodGet)
+	router.HandleFunc("/students/{id}/preferences", errorHandler(requireStudentOrAdmin(getPreferences))).Methods(http.MethodGet)
+	router.HandleFunc("/students/{id}/preferences", errorHandler(requireStudentOrAdmin(updatePreferences))).Methods(http.MethodPut)
+	router.HandleFunc("/students/{id}/notifications", errorHandler(requireStudentOrAdmin(getStudentNotifications))).Methods(http.MethodGet)
+	router.HandleFunc("/notifications/{id}/read", errorHandler(requireRole(markNotificationAsRead, auth.RoleStudent, auth.RoleAdmin))).Methods(http.MethodPut)
+
+	router.HandleFunc("/internship", errorHandler(requireRole(createInternship, auth.RoleStudent, auth.RoleAdmin))).Methods(http.MethodPost)
+	router.HandleFunc("/internship/{id}", errorHandler(requireRole(getInternship, auth.RoleStudent, auth.RoleAdmin))).Methods(http.MethodGet)
+	router.HandleFunc("/internship/{id}", errorHandler(requireRole(cancelInternship, auth.RoleStudent, auth.RoleAdmin))).Methods(http.MethodDelete)
+	router.HandleFunc("/internship/{id}/status", errorHandler(requireRole(updateInternshipStatus, auth.RoleAdmin))).Methods(http.MethodPut)
+	router.HandleFunc("/internships", errorHandler(requireRole(getInternships, auth.RoleAdmin))).Methods(http.MethodGet)
+	router.HandleFunc("/students/{id}/internships", errorHandler(requireStudentOrAdmin(getStudentInternships))).Methods(http.MethodGet)
 
 	router.HandleFunc("/offers", errorHandler(getOffersGateway)).Methods(http.MethodGet)
 	router.HandleFunc("/city-scores", errorHandler(getCityScoresGateway)).Methods(http.MethodGet)
+	router.HandleFunc("/metrics/erasmumu", errorHandler(getErasmumuMetrics)).Methods(http.MethodGet)
+	router.HandleFunc("/metrics/city-cache", errorHandler(getCityCacheMetrics)).Methods(http.MethodGet)
 
 	log.Println("Server starting on :8080")
 	log.Fatal(http.ListenAndServe(":8080", router))
@@ -141,7 +212,7 @@ func initDB() {
 		log.Fatal(err)
 	}
 
-	createTable()
+	outbox = common.NewOutbox(db, "polytech_outbox")
 }
 
 func getEnv(key, defaultValue string) string {
@@ -150,47 +221,3 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
-
-func createTable() {
-	studentsQuery := `
-	CREATE TABLE IF NOT EXISTS students (
-		id SERIAL PRIMARY KEY,
-		name VARCHAR(255) NOT NULL,
-		domain VARCHAR(255) NOT NULL
-	);
-	`
-	_, err := db.Exec(studentsQuery)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	internshipsQuery := `
-	CREATE TABLE IF NOT EXISTS internships (
-		id SERIAL PRIMARY KEY,
-		student_id INTEGER NOT NULL REFERENCES students(id),
-		offer_id INTEGER NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-	`
-	_, err = db.Exec(internshipsQuery)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	notificationsQuery := `
-	CREATE TABLE IF NOT EXISTS notifications (
-		id SERIAL PRIMARY KEY,
-		student_id INTEGER NOT NULL REFERENCES students(id),
-		type VARCHAR(64) NOT NULL,
-		offer_id INTEGER NOT NULL,
-		message TEXT NOT NULL,
-		read BOOLEAN NOT NULL DEFAULT false,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		UNIQUE(student_id, offer_id, type)
-	);
-	`
-	_, err = db.Exec(notificationsQuery)
-	if err != nil {
-		log.Fatal(err)
-	}
-}
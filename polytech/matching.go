@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/thomasrubini/polymove/common"
+	"github.com/thomasrubini/polymove/common/apierror"
+)
+
+const (
+	requirementKindSkill    = "skill"
+	requirementKindLanguage = "language"
+
+	// Mandatory requirements weigh more than optional ones in the match percentage.
+	mandatoryRequirementWeight = 2
+	optionalRequirementWeight  = 1
+)
+
+// MissingRequirement is a mandatory skill or language of an offer that a student lacks.
+type MissingRequirement struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// MissingRequirementsError rejects an internship for a student who lacks mandatory requirements of the offer.
+type MissingRequirementsError struct {
+	StudentID int
+	OfferID   int
+	Missing   []MissingRequirement
+}
+
+func (e *MissingRequirementsError) Is(target error) bool { return target == apierror.ErrConflict }
+
+func (e *MissingRequirementsError) ErrorCode() string { return "missing_requirements" }
+
+func (e *MissingRequirementsError) Error() string {
+	var skills, languages []string
+	for _, missing := range e.Missing {
+		if missing.Kind == requirementKindLanguage {
+			languages = append(languages, missing.Name)
+		} else {
+			skills = append(skills, missing.Name)
+		}
+	}
+
+	var parts []string
+	if len(skills) > 0 {
+		parts = append(parts, "skills: "+strings.Join(skills, ", "))
+	}
+	if len(languages) > 0 {
+		parts = append(parts, "languages: "+strings.Join(languages, ", "))
+	}
+	return fmt.Sprintf("student %d is missing mandatory requirements of offer %d (%s)", e.StudentID, e.OfferID, strings.Join(parts, "; "))
+}
+
+// missingRequirements lists the mandatory requirements of offer that student lacks, skills first.
+func missingRequirements(student Student, offer common.Offer) []MissingRequirement {
+	var missing []MissingRequirement
+	check := func(kind string, requirements []common.Requirement, has []string) {
+		known := skillSet(has)
+		for _, requirement := range requirements {
+			if requirement.Mandatory && !known[common.NormalizeSkill(requirement.Name)] {
+				missing = append(missing, MissingRequirement{Kind: kind, Name: requirement.Name})
+			}
+		}
+	}
+	check(requirementKindSkill, offer.Skills, student.Skills)
+	check(requirementKindLanguage, offer.Languages, student.Languages)
+	return missing
+}
+
+// matchOffer is the weighted percentage of the offer requirements the student meets; offers without
+// requirements are a full match.
+func matchOffer(student Student, offer common.Offer) int {
+	var met, total int
+	score := func(requirements []common.Requirement, has []string) {
+		known := skillSet(has)
+		for _, requirement := range requirements {
+			weight := optionalRequirementWeight
+			if requirement.Mandatory {
+				weight = mandatoryRequirementWeight
+			}
+			total += weight
+			if known[common.NormalizeSkill(requirement.Name)] {
+				met += weight
+			}
+		}
+	}
+	score(offer.Skills, student.Skills)
+	score(offer.Languages, student.Languages)
+
+	if total == 0 {
+		return 100
+	}
+	return met * 100 / total
+}
+
+func skillSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[common.NormalizeSkill(name)] = true
+	}
+	return set
+}
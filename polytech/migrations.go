@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"log"
+
+	"github.com/thomasrubini/polymove/common"
+)
+
+// migrationFiles holds the versioned schema of polytech, see common.LoadMigrations.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// newMigrator returns the migrator of the polytech schema.
+func newMigrator() *common.Migrator {
+	migrations, err := common.LoadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		log.Fatal(err)
+	}
+	return common.NewMigrator(db, "polytech", migrations)
+}
+
+// migrateDB applies pending migrations at startup.
+func migrateDB() {
+	applied, err := newMigrator().Up(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if applied > 0 {
+		log.Printf("Applied %d schema migrations", applied)
+	}
+}
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS students (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	domain VARCHAR(255) NOT NULL
+);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS internships (
+	id SERIAL PRIMARY KEY,
+	student_id INTEGER NOT NULL REFERENCES students(id),
+	offer_id INTEGER NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
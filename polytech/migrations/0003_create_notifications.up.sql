@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS notifications (
+	id SERIAL PRIMARY KEY,
+	student_id INTEGER NOT NULL REFERENCES students(id),
+	type VARCHAR(64) NOT NULL,
+	offer_id INTEGER NOT NULL,
+	message TEXT NOT NULL,
+	read BOOLEAN NOT NULL DEFAULT false,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(student_id, offer_id, type)
+);
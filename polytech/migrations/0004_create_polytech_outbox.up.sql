@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS polytech_outbox (
+	id BIGSERIAL PRIMARY KEY,
+	routing_key VARCHAR(255) NOT NULL,
+	payload JSONB NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	sent_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS polytech_outbox_pending_idx ON polytech_outbox (id) WHERE sent_at IS NULL;
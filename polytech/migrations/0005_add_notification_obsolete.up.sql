@@ -0,0 +1 @@
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS obsolete BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1 @@
+ALTER TABLE internships DROP COLUMN IF EXISTS reservation_id;
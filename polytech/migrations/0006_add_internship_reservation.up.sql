@@ -0,0 +1 @@
+ALTER TABLE internships ADD COLUMN IF NOT EXISTS reservation_id INTEGER;
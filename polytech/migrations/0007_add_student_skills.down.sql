@@ -0,0 +1,3 @@
+ALTER TABLE students
+	DROP COLUMN IF EXISTS languages,
+	DROP COLUMN IF EXISTS skills;
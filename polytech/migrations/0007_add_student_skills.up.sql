@@ -0,0 +1,4 @@
+-- Normalized names, see common.NormalizeSkills.
+ALTER TABLE students
+	ADD COLUMN IF NOT EXISTS skills TEXT[] NOT NULL DEFAULT '{}',
+	ADD COLUMN IF NOT EXISTS languages TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS internship_status_changes;
+ALTER TABLE internships
+	DROP COLUMN IF EXISTS status_changed_at,
+	DROP COLUMN IF EXISTS status;
@@ -0,0 +1,20 @@
+ALTER TABLE internships
+	ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'applied',
+	ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+-- Every status an internship went through; from_status is NULL for the initial one.
+CREATE TABLE IF NOT EXISTS internship_status_changes (
+	id SERIAL PRIMARY KEY,
+	internship_id INTEGER NOT NULL REFERENCES internships(id) ON DELETE CASCADE,
+	from_status VARCHAR(16),
+	to_status VARCHAR(16) NOT NULL,
+	reason TEXT NOT NULL DEFAULT '',
+	changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS internship_status_changes_internship_idx ON internship_status_changes (internship_id, id);
+
+-- Internships created before statuses existed start their history when they were applied for.
+UPDATE internships SET status_changed_at = created_at WHERE created_at IS NOT NULL;
+INSERT INTO internship_status_changes (internship_id, to_status, changed_at)
+SELECT id, 'applied', COALESCE(created_at, CURRENT_TIMESTAMP) FROM internships
+WHERE NOT EXISTS (SELECT 1 FROM internship_status_changes c WHERE c.internship_id = internships.id);
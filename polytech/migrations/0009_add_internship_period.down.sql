@@ -0,0 +1,5 @@
+ALTER TABLE internships DROP CONSTRAINT IF EXISTS internships_no_overlap;
+DROP INDEX IF EXISTS internships_active_offer_idx;
+ALTER TABLE internships
+	DROP COLUMN IF EXISTS end_date,
+	DROP COLUMN IF EXISTS start_date;
@@ -0,0 +1,17 @@
+-- btree_gist lets the exclusion constraint below compare student ids with =.
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+-- The offer period, copied when the internship is created.
+ALTER TABLE internships
+	ADD COLUMN IF NOT EXISTS start_date DATE,
+	ADD COLUMN IF NOT EXISTS end_date DATE;
+
+-- A student holds at most one active internship per offer and per period; rejected and cancelled internships
+-- free both. Internships created before periods were stored have no dates and are not guarded.
+CREATE UNIQUE INDEX IF NOT EXISTS internships_active_offer_idx ON internships (student_id, offer_id)
+	WHERE status NOT IN ('rejected', 'cancelled') AND start_date IS NOT NULL;
+ALTER TABLE internships DROP CONSTRAINT IF EXISTS internships_no_overlap;
+ALTER TABLE internships ADD CONSTRAINT internships_no_overlap EXCLUDE USING gist (
+	student_id WITH =,
+	daterange(start_date, end_date, '[]') WITH &&
+) WHERE (status NOT IN ('rejected', 'cancelled') AND start_date IS NOT NULL);
@@ -0,0 +1,14 @@
+-- Accounts allowed to log in; students are linked to their student record, employers to their Erasmumu employer.
+CREATE TABLE IF NOT EXISTS users (
+	id SERIAL PRIMARY KEY,
+	email VARCHAR(255) NOT NULL,
+	password_hash TEXT NOT NULL,
+	role VARCHAR(16) NOT NULL CHECK (role IN ('student', 'admin', 'employer')),
+	student_id INTEGER REFERENCES students(id) ON DELETE CASCADE,
+	employer_id INTEGER,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CHECK (role <> 'student' OR student_id IS NOT NULL),
+	CHECK (role <> 'employer' OR employer_id IS NOT NULL)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_idx ON users (LOWER(email));
@@ -0,0 +1,8 @@
+-- Per-student recommendation settings: weights maps recommendation criteria to their weight, and offers starting
+-- close to preferred_start_date fit best.
+CREATE TABLE IF NOT EXISTS student_preferences (
+	student_id INTEGER PRIMARY KEY REFERENCES students(id) ON DELETE CASCADE,
+	weights JSONB NOT NULL DEFAULT '{}',
+	preferred_start_date DATE,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
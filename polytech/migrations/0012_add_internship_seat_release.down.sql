@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS internships_seat_release_pending_idx;
+ALTER TABLE internships DROP COLUMN IF EXISTS seat_release_pending;
@@ -0,0 +1,4 @@
+-- Rejected and cancelled internships whose Erasmumu seat is still to give back: the flag is set with the status
+-- change and cleared once Erasmumu released the reservation, see releasePendingSeats.
+ALTER TABLE internships ADD COLUMN IF NOT EXISTS seat_release_pending BOOLEAN NOT NULL DEFAULT false;
+CREATE INDEX IF NOT EXISTS internships_seat_release_pending_idx ON internships (id) WHERE seat_release_pending;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS internships_domain_idx;
+DROP INDEX IF EXISTS internships_city_idx;
+ALTER TABLE internships DROP COLUMN IF EXISTS domain, DROP COLUMN IF EXISTS city;
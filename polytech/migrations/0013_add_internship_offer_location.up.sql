@@ -0,0 +1,8 @@
+-- The offer city and domain, copied when the internship is created and kept in sync by offer.updated events so
+-- GET /internships can filter on them. Internships created before are filled from Erasmumu after startup, see
+-- runInternshipOfferBackfill; '' marks an offer Erasmumu no longer knows.
+ALTER TABLE internships
+	ADD COLUMN IF NOT EXISTS city TEXT,
+	ADD COLUMN IF NOT EXISTS domain TEXT;
+CREATE INDEX IF NOT EXISTS internships_city_idx ON internships (LOWER(city));
+CREATE INDEX IF NOT EXISTS internships_domain_idx ON internships (LOWER(domain));
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/thomasrubini/polymove/common/apierror"
+)
+
+// Recommendation criteria; each is scored between 0 and 1 per offer, see criterionValues.
+const (
+	criterionMatch     = "match"
+	criterionSafety    = "safety"
+	criterionEconomy   = "economy"
+	criterionQoL       = "qol"
+	criterionCulture   = "culture"
+	criterionRelevance = "relevance"
+	criterionSalary    = "salary"
+	criterionDateFit   = "date_fit"
+)
+
+const (
+	maxCriterionWeight = 10
+
+	// weightParamPrefix prefixes the query parameters overriding one weight, as in weight_salary=3.
+	weightParamPrefix = "weight_"
+
+	// dateFitWindow is how far from the preferred start date an offer may start and still fit at all.
+	dateFitWindow = 90 * 24 * time.Hour
+	// dateFitHorizon is how far out an offer may start and still fit at all, for students without a preferred date.
+	dateFitHorizon = 365 * 24 * time.Hour
+)
+
+var recommendationCriteria = []string{
+	criterionMatch, criterionSafety, criterionEconomy, criterionQoL,
+	criterionCulture, criterionRelevance, criterionSalary, criterionDateFit,
+}
+
+// defaultRecommendationWeights count the requirements match double, so offers closest to the profile lead.
+var defaultRecommendationWeights = map[string]float64{
+	criterionMatch:     2,
+	criterionSafety:    1,
+	criterionEconomy:   1,
+	criterionQoL:       1,
+	criterionCulture:   1,
+	criterionRelevance: 1,
+	criterionSalary:    1,
+	criterionDateFit:   1,
+}
+
+// minMaxCriteria are normalized across the ranked offers: the lowest value scores 0 and the highest 1.
+var minMaxCriteria = []string{criterionSafety, criterionEconomy, criterionQoL, criterionCulture, criterionRelevance, criterionSalary}
+
+// CriterionScore explains one criterion of a recommendation: Value is the offer's normalized score and
+// Contribution the points it adds to the total.
+type CriterionScore struct {
+	Criterion    string  `json:"criterion"`
+	Weight       float64 `json:"weight"`
+	Value        float64 `json:"value"`
+	Contribution float64 `json:"contribution"`
+}
+
+// Recommendation is the weighted score of an offer for a student, between 0 and 100; it is the sum of the
+// contributions of its criteria.
+type Recommendation struct {
+	Score    float64          `json:"score"`
+	Criteria []CriterionScore `json:"criteria"`
+}
+
+// StudentPreferences are the stored recommendation settings of a student.
+type StudentPreferences struct {
+	StudentID          int                `json:"student_id"`
+	Weights            map[string]float64 `json:"weights"`
+	PreferredStartDate string             `json:"preferred_start_date,omitempty"`
+	UpdatedAt          string             `json:"updated_at,omitempty"`
+}
+
+// recommendationSettings are the weights and preferred start date a ranking uses.
+type recommendationSettings struct {
+	Weights        map[string]float64
+	PreferredStart time.Time
+}
+
+// canonicalCriterion resolves the aliases accepted by sort_by and weight parameters.
+func canonicalCriterion(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "quality_of_life" {
+		return criterionQoL
+	}
+	return name
+}
+
+func knownCriterion(name string) bool {
+	for _, criterion := range recommendationCriteria {
+		if criterion == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validateWeights checks that weights only name known criteria with weights between 0 and maxCriterionWeight.
+func validateWeights(weights map[string]float64) error {
+	for criterion, weight := range weights {
+		if !knownCriterion(criterion) {
+			return apierror.Validation("invalid_weights", "unknown recommendation criterion %q: expected one of %s", criterion, strings.Join(recommendationCriteria, ", "))
+		}
+		if math.IsNaN(weight) || weight < 0 || weight > maxCriterionWeight {
+			return apierror.Validation("invalid_weights", "weight of %s must be between 0 and %d", criterion, maxCriterionWeight)
+		}
+	}
+	return nil
+}
+
+// resolveRecommendationSettings layers the ranking settings: defaults, then the student's stored preferences,
+// then the query. sort_by ranks on a single criterion, as before weights existed; weight_<criterion> and
+// preferred_start override single settings.
+func resolveRecommendationSettings(query url.Values, preferences StudentPreferences) (recommendationSettings, error) {
+	settings := recommendationSettings{Weights: make(map[string]float64, len(recommendationCriteria))}
+	for criterion, weight := range defaultRecommendationWeights {
+		settings.Weights[criterion] = weight
+	}
+	for criterion, weight := range preferences.Weights {
+		settings.Weights[criterion] = weight
+	}
+	if preferences.PreferredStartDate != "" {
+		settings.PreferredStart, _ = time.Parse("2006-01-02", preferences.PreferredStartDate)
+	}
+
+	if sortBy := canonicalCriterion(query.Get("sort_by")); sortBy != "" {
+		if !knownCriterion(sortBy) {
+			return settings, apierror.Validation("invalid_query", "invalid sort_by %q: expected one of %s", query.Get("sort_by"), strings.Join(recommendationCriteria, ", "))
+		}
+		for criterion := range settings.Weights {
+			settings.Weights[criterion] = 0
+		}
+		settings.Weights[sortBy] = 1
+	}
+
+	for key := range query {
+		if !strings.HasPrefix(key, weightParamPrefix) {
+			continue
+		}
+		criterion := canonicalCriterion(strings.TrimPrefix(key, weightParamPrefix))
+		weight, err := strconv.ParseFloat(query.Get(key), 64)
+		if err != nil {
+			return settings, apierror.Validation("invalid_weights", "invalid %s: expected a number", key)
+		}
+		if err := validateWeights(map[string]float64{criterion: weight}); err != nil {
+			return settings, err
+		}
+		settings.Weights[criterion] = weight
+	}
+
+	if raw := query.Get("preferred_start"); raw != "" {
+		start, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return settings, apierror.Validation("invalid_query", "invalid preferred_start: expected YYYY-MM-DD")
+		}
+		settings.PreferredStart = start
+	}
+
+	total := 0.0
+	for _, weight := range settings.Weights {
+		total += weight
+	}
+	if total == 0 {
+		return settings, apierror.Validation("invalid_weights", "at least one recommendation weight must be positive")
+	}
+	return settings, nil
+}
+
+// rawCriterionValue returns the unnormalized value of a min-max criterion; ok is false when the offer lacks it,
+// as for cities MI8 has no scores for.
+func rawCriterionValue(offer *OfferWithScore, criterion string) (value float64, ok bool) {
+	if criterion == criterionSalary {
+		return float64(offer.SalaryEURMonthly), true
+	}
+	if offer.Scores == nil {
+		return 0, false
+	}
+	switch criterion {
+	case criterionSafety:
+		return offer.Scores.Safety, true
+	case criterionEconomy:
+		return offer.Scores.Economy, true
+	case criterionQoL:
+		return offer.Scores.QoL, true
+	case criterionCulture:
+		return offer.Scores.Culture, true
+	case criterionRelevance:
+		return offer.Scores.Relevance, true
+	}
+	return 0, false
+}
+
+// dateFit scores how well an offer's start date suits the student: 1 on the preferred date, falling to 0
+// dateFitWindow away from it. Without a preferred date, offers starting sooner fit better and offers that already
+// started do not fit.
+func dateFit(startDate string, preferred, now time.Time) float64 {
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return 0
+	}
+	if !preferred.IsZero() {
+		return math.Max(0, 1-math.Abs(float64(start.Sub(preferred)))/float64(dateFitWindow))
+	}
+	if start.Before(now) {
+		return 0
+	}
+	return math.Max(0, 1-float64(start.Sub(now))/float64(dateFitHorizon))
+}
+
+// criterionValues scores every offer on every criterion between 0 and 1. Min-max criteria compare offers with
+// each other; when all offers are equal on one, they all get 1 unless they all lack it.
+func criterionValues(offers []*OfferWithScore, preferredStart, now time.Time) []map[string]float64 {
+	values := make([]map[string]float64, len(offers))
+	for i, offer := range offers {
+		values[i] = map[string]float64{criterionDateFit: dateFit(offer.StartDate, preferredStart, now)}
+		if offer.Match != nil {
+			values[i][criterionMatch] = float64(*offer.Match) / 100
+		}
+	}
+
+	for _, criterion := range minMaxCriteria {
+		low, high, found := math.Inf(1), math.Inf(-1), false
+		for _, offer := range offers {
+			if value, ok := rawCriterionValue(offer, criterion); ok {
+				low, high, found = math.Min(low, value), math.Max(high, value), true
+			}
+		}
+		if !found {
+			continue
+		}
+		for i, offer := range offers {
+			value, ok := rawCriterionValue(offer, criterion)
+			switch {
+			case !ok:
+			case high > low:
+				values[i][criterion] = (value - low) / (high - low)
+			case high > 0:
+				values[i][criterion] = 1
+			}
+		}
+	}
+	return values
+}
+
+// rankOffers sets the recommendation of every offer and sorts them best first; ties go to the best match.
+func rankOffers(offers []*OfferWithScore, settings recommendationSettings, now time.Time) {
+	total := 0.0
+	for _, weight := range settings.Weights {
+		total += weight
+	}
+
+	values := criterionValues(offers, settings.PreferredStart, now)
+	for i, offer := range offers {
+		recommendation := &Recommendation{Criteria: []CriterionScore{}}
+		for _, criterion := range recommendationCriteria {
+			weight := settings.Weights[criterion]
+			if weight == 0 {
+				continue
+			}
+			contribution := weight * values[i][criterion] / total * 100
+			recommendation.Score += contribution
+			recommendation.Criteria = append(recommendation.Criteria, CriterionScore{
+				Criterion:    criterion,
+				Weight:       weight,
+				Value:        roundScore(values[i][criterion]),
+				Contribution: roundScore(contribution),
+			})
+		}
+		recommendation.Score = roundScore(recommendation.Score)
+		offer.Recommendation = recommendation
+	}
+
+	sort.SliceStable(offers, func(i, j int) bool {
+		a, b := offers[i], offers[j]
+		if a.Recommendation.Score != b.Recommendation.Score {
+			return a.Recommendation.Score > b.Recommendation.Score
+		}
+		return matchOf(a) > matchOf(b)
+	})
+}
+
+func matchOf(offer *OfferWithScore) int {
+	if offer.Match == nil {
+		return 0
+	}
+	return *offer.Match
+}
+
+// roundScore keeps two decimals, enough to explain a ranking.
+func roundScore(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// getStudentPreferences returns the stored preferences of a student, with no weights when none are stored.
+func getStudentPreferences(studentID int) (StudentPreferences, error) {
+	preferences := StudentPreferences{StudentID: studentID, Weights: map[string]float64{}}
+
+	var (
+		weights   []byte
+		startDate sql.NullString
+	)
+	err := db.QueryRow(
+		"SELECT weights, TO_CHAR(preferred_start_date, 'YYYY-MM-DD'), TO_CHAR(updated_at, 'YYYY-MM-DD\"T\"HH24:MI:SS\"Z\"') FROM student_preferences WHERE student_id = $1",
+		studentID,
+	).Scan(&weights, &startDate, &preferences.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return preferences, nil
+	}
+	if err != nil {
+		return preferences, fmt.Errorf("failed to get student preferences: %w", err)
+	}
+	if err := json.Unmarshal(weights, &preferences.Weights); err != nil {
+		return preferences, fmt.Errorf("invalid stored weights of student %d: %w", studentID, err)
+	}
+	preferences.PreferredStartDate = startDate.String
+	return preferences, nil
+}
+
+// getPreferences handles GET /students/{id}/preferences - Returns the weights recommendations use for a student
+func getPreferences(w http.ResponseWriter, r *http.Request) error {
+	studentID, err := studentIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	if err := checkStudentExists(studentID); err != nil {
+		return err
+	}
+
+	preferences, err := effectivePreferences(studentID)
+	if err != nil {
+		return err
+	}
+
+	return NewResponseWriter(w).JSON(http.StatusOK, preferences)
+}
+
+// effectivePreferences returns the stored preferences of a student with every weight in effect, not only the
+// stored ones.
+func effectivePreferences(studentID int) (StudentPreferences, error) {
+	preferences, err := getStudentPreferences(studentID)
+	if err != nil {
+		return preferences, err
+	}
+
+	settings, err := resolveRecommendationSettings(url.Values{}, preferences)
+	if err != nil {
+		return preferences, err
+	}
+	preferences.Weights = settings.Weights
+	return preferences, nil
+}
+
+// updatePreferences handles PUT /students/{id}/preferences - Stores the recommendation weights of a student
+func updatePreferences(w http.ResponseWriter, r *http.Request) error {
+	studentID, err := studentIDFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	var preferences StudentPreferences
+	if err := json.NewDecoder(r.Body).Decode(&preferences); err != nil {
+		return apierror.Validation("invalid_body", "failed to decode request body: %v", err)
+	}
+	if preferences.Weights == nil {
+		preferences.Weights = map[string]float64{}
+	}
+	weights := make(map[string]float64, len(preferences.Weights))
+	for criterion, weight := range preferences.Weights {
+		weights[canonicalCriterion(criterion)] = weight
+	}
+	if err := validateWeights(weights); err != nil {
+		return err
+	}
+	// Rejects weights that would leave nothing to rank on.
+	if _, err := resolveRecommendationSettings(url.Values{}, StudentPreferences{Weights: weights}); err != nil {
+		return err
+	}
+
+	var startDate interface{}
+	if preferences.PreferredStartDate != "" {
+		if _, err := time.Parse("2006-01-02", preferences.PreferredStartDate); err != nil {
+			return apierror.Validation("invalid_body", "invalid preferred_start_date: expected YYYY-MM-DD")
+		}
+		startDate = preferences.PreferredStartDate
+	}
+
+	encoded, err := json.Marshal(weights)
+	if err != nil {
+		return fmt.Errorf("failed to encode weights: %w", err)
+	}
+
+	_, err = db.Exec(
+		`INSERT INTO student_preferences (student_id, weights, preferred_start_date, updated_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		ON CONFLICT (student_id) DO UPDATE SET weights = EXCLUDED.weights, preferred_start_date = EXCLUDED.preferred_start_date, updated_at = EXCLUDED.updated_at`,
+		studentID, string(encoded), startDate,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return studentNotFound(studentID)
+		}
+		return fmt.Errorf("failed to store student preferences: %w", err)
+	}
+
+	stored, err := effectivePreferences(studentID)
+	if err != nil {
+		return err
+	}
+
+	return NewResponseWriter(w).JSON(http.StatusOK, stored)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/thomasrubini/polymove/common"
+	"github.com/thomasrubini/polymove/common/apierror"
+)
+
+// reservationHolder identifies a student in Erasmumu reservations.
+func reservationHolder(studentID int) string {
+	return fmt.Sprintf("polytech:student:%d", studentID)
+}
+
+// ReserveSeat holds one seat of an offer for a student. Holding twice takes two seats, so it is never retried.
+func (c *ErasmumuClient) ReserveSeat(ctx context.Context, offerID, studentID int) (common.Reservation, error) {
+	body, err := json.Marshal(map[string]interface{}{"holder": reservationHolder(studentID)})
+	if err != nil {
+		return common.Reservation{}, fmt.Errorf("failed to marshal reservation request: %w", err)
+	}
+	return c.callReservationAPI(ctx, "ReserveSeat", false, fmt.Sprintf("/offers/%d/reservations", offerID), body)
+}
+
+// ConfirmSeat turns a held seat into a taken one.
+func (c *ErasmumuClient) ConfirmSeat(ctx context.Context, reservationID int) (common.Reservation, error) {
+	return c.callReservationAPI(ctx, "ConfirmSeat", true, fmt.Sprintf("/reservations/%d/confirm", reservationID), nil)
+}
+
+// ReleaseSeat gives a held or taken seat back to the offer.
+func (c *ErasmumuClient) ReleaseSeat(ctx context.Context, reservationID int) (common.Reservation, error) {
+	return c.callReservationAPI(ctx, "ReleaseSeat", true, fmt.Sprintf("/reservations/%d/release", reservationID), nil)
+}
+
+// callReservationAPI POSTs to an Erasmumu reservation endpoint and decodes the reservation it returns.
+func (c *ErasmumuClient) callReservationAPI(ctx context.Context, name string, idempotent bool, path string, body []byte) (common.Reservation, error) {
+	var reservation common.Reservation
+	err := c.call(ctx, name, idempotent, func(ctx context.Context) error {
+		var err error
+		reservation, err = c.postReservation(ctx, path, body)
+		return err
+	})
+	return reservation, err
+}
+
+func (c *ErasmumuClient) postReservation(ctx context.Context, path string, body []byte) (common.Reservation, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return common.Reservation{}, fmt.Errorf("failed to build reservation request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.Token != nil {
+		token, err := c.Token()
+		if err != nil {
+			return common.Reservation{}, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return common.Reservation{}, fmt.Errorf("%w: %v", errErasmumuUnavailable, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return common.Reservation{}, fmt.Errorf("%w: status %d", errErasmumuUnavailable, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		var errResp ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		switch resp.StatusCode {
+		case http.StatusNotFound:
+			return common.Reservation{}, apierror.NotFound("reservation_not_found", "erasmumu: %s", errResp.Message)
+		case http.StatusConflict, http.StatusUnprocessableEntity:
+			return common.Reservation{}, apierror.Conflict("reservation_rejected", "erasmumu rejected reservation: %s", errResp.Message)
+		}
+		return common.Reservation{}, fmt.Errorf("erasmumu rejected reservation (status %d): %s", resp.StatusCode, errResp.Message)
+	}
+
+	var reservation common.Reservation
+	if err := json.NewDecoder(resp.Body).Decode(&reservation); err != nil {
+		return common.Reservation{}, fmt.Errorf("failed to decode reservation response: %w", err)
+	}
+	return reservation, nil
+}
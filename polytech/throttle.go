@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thomasrubini/polymove/common/apierror"
+)
+
+const (
+	// maxConcurrentLogins caps the password hashes checked at once: each takes a CPU core for a while, and login
+	// is open to anyone.
+	maxConcurrentLogins = 4
+	// loginQueueTimeout is how long a login waits for a free slot before being turned away.
+	loginQueueTimeout = 5 * time.Second
+
+	// maxFailedLogins failed logins for one email within failedLoginWindow lock it until the window ends.
+	maxFailedLogins   = 5
+	failedLoginWindow = 15 * time.Minute
+	// failedLoginSweepSize is the number of tracked emails beyond which expired ones are dropped.
+	failedLoginSweepSize = 10000
+)
+
+// loginSlots holds one token per password check in progress.
+var loginSlots = make(chan struct{}, maxConcurrentLogins)
+
+// failedLogins counts recent failed logins by email.
+var failedLogins = newLoginThrottle(maxFailedLogins, failedLoginWindow)
+
+// acquireLoginSlot waits for a free password check slot; release it with releaseLoginSlot.
+func acquireLoginSlot(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, loginQueueTimeout)
+	defer cancel()
+
+	select {
+	case loginSlots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return apierror.TooManyRequests("login_busy", "too many logins in progress, retry shortly")
+	}
+}
+
+func releaseLoginSlot() {
+	<-loginSlots
+}
+
+type loginFailures struct {
+	count int
+	since time.Time
+}
+
+// loginThrottle locks a key after max failures within window, until the window of the first failure ends.
+type loginThrottle struct {
+	mu       sync.Mutex
+	max      int
+	window   time.Duration
+	failures map[string]*loginFailures
+}
+
+func newLoginThrottle(max int, window time.Duration) *loginThrottle {
+	return &loginThrottle{max: max, window: window, failures: make(map[string]*loginFailures)}
+}
+
+func loginThrottleKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// retryAfter returns how long key stays locked, or 0 when it may try to log in.
+func (t *loginThrottle) retryAfter(key string, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	failures, ok := t.failures[key]
+	if !ok || failures.count < t.max {
+		return 0
+	}
+	if wait := failures.since.Add(t.window).Sub(now); wait > 0 {
+		return wait
+	}
+	delete(t.failures, key)
+	return 0
+}
+
+// fail records a failed login for key.
+func (t *loginThrottle) fail(key string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	failures, ok := t.failures[key]
+	if !ok || now.Sub(failures.since) >= t.window {
+		if len(t.failures) >= failedLoginSweepSize {
+			t.sweep(now)
+		}
+		failures = &loginFailures{since: now}
+		t.failures[key] = failures
+	}
+	failures.count++
+}
+
+// succeed forgets the failures of key.
+func (t *loginThrottle) succeed(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.failures, key)
+}
+
+func (t *loginThrottle) sweep(now time.Time) {
+	for key, failures := range t.failures {
+		if now.Sub(failures.since) >= t.window {
+			delete(t.failures, key)
+		}
+	}
+}